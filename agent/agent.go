@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"preemptible-lifecycle-scheduler/logging"
+	"strings"
+	"time"
+)
+
+// DefaultMetadataURL return TRUE once compute engine has sent the preemption notice to the instance
+const DefaultMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/preempted"
+
+var (
+	PollInterval = 1 * time.Second
+	// DrainTimeout leave a few seconds of the 30 seconds notice for the kubelet to stop the pods
+	DrainTimeout = 25 * time.Second
+)
+
+type ClusterClient interface {
+	HandlePreemption(ctx context.Context, nodeName string) error
+}
+
+// Agent run on every managed node, it polls instance metadata and drains the node
+// as soon as the instance is about to be preempted
+type Agent struct {
+	Cluster     ClusterClient
+	NodeName    string
+	MetadataURL string
+	HTTPClient  *http.Client
+}
+
+func NewAgent(cluster ClusterClient, nodeName string) *Agent {
+	return &Agent{
+		Cluster:     cluster,
+		NodeName:    nodeName,
+		MetadataURL: DefaultMetadataURL,
+		HTTPClient: &http.Client{
+			Timeout: PollInterval,
+		},
+	}
+}
+
+// Run poll metadata until the instance is preempted or the context is done,
+// the node is drained once when the notice is received.
+func (a *Agent) Run(ctx context.Context) error {
+	logger := logging.Node(a.NodeName, "")
+	logger.Info("watching preemption notice of node")
+	for {
+		preempted, err := a.IsPreempted(ctx)
+		if err != nil {
+			logger.Warnf("failed to check preemption notice: %v", err)
+		}
+
+		if preempted {
+			drainCtx, cancel := context.WithTimeout(ctx, DrainTimeout)
+			err = a.Cluster.HandlePreemption(drainCtx, a.NodeName)
+			cancel()
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+func (a *Agent) IsPreempted(ctx context.Context) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, a.MetadataURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("metadata server returned %s", resp.Status)
+	}
+
+	return strings.TrimSpace(string(body)) == "TRUE", nil
+}
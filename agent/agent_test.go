@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// metadataServer is a stand-in for compute engine metadata server
+type metadataServer struct {
+	mu        sync.Mutex
+	preempted bool
+}
+
+func (s *metadataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Metadata-Flavor") != "Google" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.preempted {
+		_, _ = w.Write([]byte("TRUE"))
+		return
+	}
+	_, _ = w.Write([]byte("FALSE"))
+}
+
+func (s *metadataServer) preempt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preempted = true
+}
+
+type mockClusterClient struct {
+	handled chan string
+}
+
+func (c *mockClusterClient) HandlePreemption(ctx context.Context, nodeName string) error {
+	c.handled <- nodeName
+	return nil
+}
+
+func TestAgent_Run(t *testing.T) {
+	PollInterval = 5 * time.Millisecond
+
+	metadata := &metadataServer{}
+	server := httptest.NewServer(metadata)
+	defer server.Close()
+
+	cc := &mockClusterClient{handled: make(chan string, 1)}
+	a := NewAgent(cc, "node-a")
+	a.MetadataURL = server.URL
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(context.Background())
+	}()
+
+	select {
+	case nodeName := <-cc.handled:
+		t.Fatalf("expected no preemption, got %s handled", nodeName)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	metadata.preempt()
+
+	select {
+	case nodeName := <-cc.handled:
+		if nodeName != "node-a" {
+			t.Errorf("expected node-a, got %s", nodeName)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected preemption to be handled")
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestAgent_Run_Cancelled(t *testing.T) {
+	PollInterval = 5 * time.Millisecond
+
+	server := httptest.NewServer(&metadataServer{})
+	defer server.Close()
+
+	a := NewAgent(&mockClusterClient{handled: make(chan string, 1)}, "node-a")
+	a.MetadataURL = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := a.Run(ctx); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
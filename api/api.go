@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"net/http"
+	"preemptible-lifecycle-scheduler/approval"
+	"preemptible-lifecycle-scheduler/scheduler"
+	"strings"
+	"time"
+)
+
+const (
+	adminNodesPrefix     = "/admin/nodes/"
+	adminApprovalsPrefix = "/admin/approvals/"
+)
+
+type Scheduler interface {
+	GetPlan() (*scheduler.Plan, error)
+	Pause(until time.Time, reason string) error
+	Resume() error
+	RecycleNode(nodeName string) error
+	SkipNode(nodeName string) error
+}
+
+// Approvals list pending steps and take operator decisions on them
+type Approvals interface {
+	Pending() []approval.Request
+	Decide(node string, step string, approved bool) error
+}
+
+type pauseRequest struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+type response struct {
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Register add the read-only status endpoint and, when a token is set, the admin endpoints to the mux.
+// Admin requests must carry the token as a bearer token. Approval endpoints are added when approvals is not nil.
+func Register(mux *http.ServeMux, s Scheduler, approvals Approvals, adminToken string) {
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+			return
+		}
+
+		plan, err := s.GetPlan()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, response{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, plan)
+	})
+
+	if approvals != nil {
+		mux.HandleFunc("/approvals", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				writeJSON(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+				return
+			}
+
+			writeJSON(w, http.StatusOK, approvals.Pending())
+		})
+	}
+
+	if adminToken == "" {
+		log.Warn("admin-token is not set, admin api is disabled")
+		return
+	}
+
+	if approvals != nil {
+		// /admin/approvals/<node>/<step>/approve and /admin/approvals/<node>/<step>/reject
+		mux.Handle(adminApprovalsPrefix, admin(adminToken, func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(strings.TrimPrefix(r.URL.Path, adminApprovalsPrefix), "/")
+			if len(parts) != 3 || (parts[2] != "approve" && parts[2] != "reject") {
+				writeJSON(w, http.StatusNotFound, response{Error: "not found"})
+				return
+			}
+
+			approved := parts[2] == "approve"
+			writeResult(w, approvals.Decide(parts[0], parts[1], approved), parts[2]+"d")
+		}))
+	}
+
+	mux.Handle("/admin/pause", admin(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		var req pauseRequest
+		if r.ContentLength != 0 {
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+				return
+			}
+		}
+
+		// a pause ending in the past would not pause anything
+		if !req.Until.IsZero() && !req.Until.After(time.Now()) {
+			writeJSON(w, http.StatusBadRequest, response{Error: "until is in the past"})
+			return
+		}
+
+		writeResult(w, s.Pause(req.Until, req.Reason), "paused")
+	}))
+
+	mux.Handle("/admin/resume", admin(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, s.Resume(), "resumed")
+	}))
+
+	// /admin/nodes/<node>/recycle and /admin/nodes/<node>/skip
+	mux.Handle(adminNodesPrefix, admin(adminToken, func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, adminNodesPrefix), "/")
+		if len(parts) != 2 || parts[0] == "" {
+			writeJSON(w, http.StatusNotFound, response{Error: "not found"})
+			return
+		}
+
+		nodeName := parts[0]
+		switch parts[1] {
+		case scheduler.ActionRecycle:
+			err := s.RecycleNode(nodeName)
+			if err == nil {
+				writeJSON(w, http.StatusAccepted, response{Status: "recycling"})
+				return
+			}
+			writeResult(w, err, "")
+		case scheduler.ActionSkip:
+			writeResult(w, s.SkipNode(nodeName), "skipped")
+		default:
+			writeJSON(w, http.StatusNotFound, response{Error: "not found"})
+		}
+	}))
+}
+
+// admin only let authenticated POST requests through
+func admin(token string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		given := strings.TrimPrefix(authorization, "Bearer ")
+		if given == authorization || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, response{Error: "unauthorized"})
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+			return
+		}
+
+		log.WithField("remote", r.RemoteAddr).Infof("admin request %s", r.URL.Path)
+		next(w, r)
+	})
+}
+
+func writeResult(w http.ResponseWriter, err error, status string) {
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, response{Status: status})
+	case errors.Is(err, scheduler.ErrNodeInFlight):
+		writeJSON(w, http.StatusConflict, response{Error: err.Error()})
+	case errors.Is(err, scheduler.ErrNodeNotManaged), errors.Is(err, approval.ErrNotFound), apierrors.IsNotFound(err):
+		writeJSON(w, http.StatusNotFound, response{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, response{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Errorf("failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"preemptible-lifecycle-scheduler/approval"
+	"preemptible-lifecycle-scheduler/scheduler"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockScheduler struct {
+	pausedUntil time.Time
+	recycled    []string
+	skipped     []string
+}
+
+func (s *mockScheduler) GetPlan() (*scheduler.Plan, error) {
+	return &scheduler.Plan{PeakState: scheduler.OutsidePeakHour}, nil
+}
+
+func (s *mockScheduler) Pause(until time.Time, reason string) error {
+	s.pausedUntil = until
+	return nil
+}
+
+func (s *mockScheduler) Resume() error {
+	return nil
+}
+
+func (s *mockScheduler) RecycleNode(nodeName string) error {
+	if nodeName == "busy" {
+		return scheduler.ErrNodeInFlight
+	}
+
+	s.recycled = append(s.recycled, nodeName)
+	return nil
+}
+
+func (s *mockScheduler) SkipNode(nodeName string) error {
+	s.skipped = append(s.skipped, nodeName)
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	tests := map[string]struct {
+		Method        string
+		Path          string
+		Token         string
+		Authorization string
+		Body          string
+		Expected      int
+	}{
+		"status": {
+			Method:   http.MethodGet,
+			Path:     "/status",
+			Expected: http.StatusOK,
+		},
+		"pause without token": {
+			Method:   http.MethodPost,
+			Path:     "/admin/pause",
+			Expected: http.StatusUnauthorized,
+		},
+		"pause with wrong token": {
+			Method:   http.MethodPost,
+			Path:     "/admin/pause",
+			Token:    "wrong",
+			Expected: http.StatusUnauthorized,
+		},
+		"pause until": {
+			Method:   http.MethodPost,
+			Path:     "/admin/pause",
+			Token:    "secret",
+			Body:     `{"until": "2100-01-01T10:00:00Z", "reason": "incident"}`,
+			Expected: http.StatusOK,
+		},
+		"pause until in the past": {
+			Method:   http.MethodPost,
+			Path:     "/admin/pause",
+			Token:    "secret",
+			Body:     `{"until": "2020-01-01T10:00:00Z", "reason": "incident"}`,
+			Expected: http.StatusBadRequest,
+		},
+		"pause with token without bearer scheme": {
+			Method:        http.MethodPost,
+			Path:          "/admin/pause",
+			Authorization: "secret",
+			Expected:      http.StatusUnauthorized,
+		},
+		"pause with get": {
+			Method:   http.MethodGet,
+			Path:     "/admin/pause",
+			Token:    "secret",
+			Expected: http.StatusMethodNotAllowed,
+		},
+		"resume": {
+			Method:   http.MethodPost,
+			Path:     "/admin/resume",
+			Token:    "secret",
+			Expected: http.StatusOK,
+		},
+		"recycle": {
+			Method:   http.MethodPost,
+			Path:     "/admin/nodes/node-a/recycle",
+			Token:    "secret",
+			Expected: http.StatusAccepted,
+		},
+		"recycle in flight": {
+			Method:   http.MethodPost,
+			Path:     "/admin/nodes/busy/recycle",
+			Token:    "secret",
+			Expected: http.StatusConflict,
+		},
+		"skip": {
+			Method:   http.MethodPost,
+			Path:     "/admin/nodes/node-a/skip",
+			Token:    "secret",
+			Expected: http.StatusOK,
+		},
+		"approvals": {
+			Method:   http.MethodGet,
+			Path:     "/approvals",
+			Expected: http.StatusOK,
+		},
+		"approve missing step": {
+			Method:   http.MethodPost,
+			Path:     "/admin/approvals/node-a/cordon/approve",
+			Token:    "secret",
+			Expected: http.StatusNotFound,
+		},
+		"unknown node action": {
+			Method:   http.MethodPost,
+			Path:     "/admin/nodes/node-a/delete",
+			Token:    "secret",
+			Expected: http.StatusNotFound,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			Register(mux, &mockScheduler{}, approval.NewGate(time.Minute, approval.TimeoutPolicyReject), "secret")
+
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+			if tc.Token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.Token)
+			}
+			if tc.Authorization != "" {
+				req.Header.Set("Authorization", tc.Authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, req)
+			if recorder.Code != tc.Expected {
+				t.Errorf("expected %v, got %v: %s", tc.Expected, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegister_AdminDisabled(t *testing.T) {
+	mux := http.NewServeMux()
+	Register(mux, &mockScheduler{}, nil, "")
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/resume", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected %v, got %v", http.StatusNotFound, recorder.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"preemptible-lifecycle-scheduler/logging"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	TimeoutPolicyApprove = "approve"
+	TimeoutPolicyReject  = "reject"
+)
+
+var (
+	ErrRejected = errors.New("step rejected")
+	ErrNotFound = errors.New("no pending approval for the step")
+)
+
+// Request is a step of a node waiting for an operator decision
+type Request struct {
+	Node        string    `json:"node"`
+	Step        string    `json:"step"`
+	RequestedAt time.Time `json:"requestedAt"`
+	Deadline    time.Time `json:"deadline"`
+}
+
+type pending struct {
+	request  Request
+	decision chan bool
+}
+
+// Gate hold every step until it is approved or rejected, steps nobody answers within Timeout are
+// decided by TimeoutPolicy.
+type Gate struct {
+	Timeout       time.Duration
+	TimeoutPolicy string
+
+	mu      sync.Mutex
+	pending map[string]*pending
+}
+
+func NewGate(timeout time.Duration, timeoutPolicy string) *Gate {
+	return &Gate{
+		Timeout:       timeout,
+		TimeoutPolicy: timeoutPolicy,
+		pending:       make(map[string]*pending),
+	}
+}
+
+func key(node string, step string) string {
+	return node + "/" + step
+}
+
+// Approve block until the step is decided, return ErrRejected when it is rejected
+func (g *Gate) Approve(ctx context.Context, node string, step string) error {
+	now := time.Now()
+	p := &pending{
+		request: Request{
+			Node:        node,
+			Step:        step,
+			RequestedAt: now,
+			Deadline:    now.Add(g.Timeout),
+		},
+		decision: make(chan bool, 1),
+	}
+
+	g.mu.Lock()
+	g.pending[key(node, step)] = p
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.pending, key(node, step))
+		g.mu.Unlock()
+	}()
+
+	logger := logging.Node(node, "").WithFields(log.Fields{
+		logging.FieldAction:   step,
+		logging.FieldDeadline: logging.Deadline(p.request.Deadline),
+	})
+	logger.Info("waiting for approval")
+
+	timer := time.NewTimer(g.Timeout)
+	defer timer.Stop()
+
+	select {
+	case approved := <-p.decision:
+		if !approved {
+			logger.Warn("step rejected")
+			return ErrRejected
+		}
+		logger.Info("step approved")
+		return nil
+
+	case <-timer.C:
+		if g.TimeoutPolicy == TimeoutPolicyApprove {
+			logger.Warn("approval timed out, approving by policy")
+			return nil
+		}
+		logger.Warn("approval timed out, rejecting by policy")
+		return ErrRejected
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Decide approve or reject a pending step
+func (g *Gate) Decide(node string, step string, approved bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.pending[key(node, step)]
+	if !ok {
+		return ErrNotFound
+	}
+
+	select {
+	case p.decision <- approved:
+	default:
+		// already decided, waiting for Approve to pick it up
+	}
+	return nil
+}
+
+// Pending list steps waiting for a decision, oldest first
+func (g *Gate) Pending() []Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	requests := make([]Request, 0)
+	for _, p := range g.pending {
+		requests = append(requests, p.request)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+	})
+	return requests
+}
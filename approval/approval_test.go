@@ -0,0 +1,71 @@
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGate_Approve(t *testing.T) {
+	tests := map[string]struct {
+		Decision      *bool
+		TimeoutPolicy string
+		Expected      error
+	}{
+		"approved": {
+			Decision: func() *bool { b := true; return &b }(),
+			Expected: nil,
+		},
+		"rejected": {
+			Decision: func() *bool { b := false; return &b }(),
+			Expected: ErrRejected,
+		},
+		"timeout approve": {
+			TimeoutPolicy: TimeoutPolicyApprove,
+			Expected:      nil,
+		},
+		"timeout reject": {
+			TimeoutPolicy: TimeoutPolicyReject,
+			Expected:      ErrRejected,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gate := NewGate(100*time.Millisecond, tc.TimeoutPolicy)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- gate.Approve(context.Background(), "node-a", "cordon")
+			}()
+
+			if tc.Decision != nil {
+				for len(gate.Pending()) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+
+				err := gate.Decide("node-a", "cordon", *tc.Decision)
+				if err != nil {
+					t.Fatalf("failed to decide: %v", err)
+				}
+			}
+
+			err := <-done
+			if err != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, err)
+			}
+
+			if len(gate.Pending()) != 0 {
+				t.Errorf("expected no pending approvals, got %v", gate.Pending())
+			}
+		})
+	}
+}
+
+func TestGate_Decide_NotFound(t *testing.T) {
+	gate := NewGate(time.Minute, TimeoutPolicyReject)
+	err := gate.Decide("node-a", "drain", true)
+	if err != ErrNotFound {
+		t.Errorf("expected %v, got %v", ErrNotFound, err)
+	}
+}
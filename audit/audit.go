@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// SinkStdout write records to standard output, application logs go to standard error
+const SinkStdout = "stdout"
+
+const (
+	RuleExpiresBeforePeakEnd  = "expires-before-peak-end"
+	RuleExpiresWithinGraceful = "expires-within-graceful-period"
+	RuleSurvivesPeakHour      = "survives-peak-hour"
+	RuleNotExpiring           = "not-expiring"
+	RuleSkippedByOperator     = "skipped-by-operator"
+	RulePaused                = "paused"
+	RuleRecycleRequested      = "recycle-requested"
+	RuleZoneLimit             = "zone-limit"
+	RuleEvictionNotice        = "eviction-notice"
+	RuleInFlight              = "in-flight"
+)
+
+// Record is one scheduler decision about a node, written as a single json line
+type Record struct {
+	Time           time.Time `json:"time"`
+	Node           string    `json:"node"`
+	CreatedAt      time.Time `json:"createdAt"`
+	ExpiredAt      time.Time `json:"expiredAt"`
+	PeakState      string    `json:"peakState"`
+	Rule           string    `json:"rule"`
+	GracefulPeriod string    `json:"gracefulPeriod"`
+	Action         string    `json:"action"`
+	Outcome        string    `json:"outcome,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Logger append records to a sink, it is safe for concurrent use
+type Logger struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+// Open return a logger writing to stdout or appending to the file at path
+func Open(path string) (*Logger, error) {
+	if path == "" || path == SinkStdout {
+		return NewLogger(os.Stdout), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Logger{writer: file, closer: file}, nil
+}
+
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{writer: w}
+}
+
+// Write append the record, time is set when empty
+func (l *Logger) Write(record Record) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.writer.Write(append(data, '\n'))
+	return err
+}
+
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
+
+// Filter select records by node and decision time, zero values match everything
+type Filter struct {
+	Node  string
+	Since time.Time
+	Until time.Time
+}
+
+func (f Filter) Match(record Record) bool {
+	if f.Node != "" && record.Node != f.Node {
+		return false
+	}
+
+	if !f.Since.IsZero() && record.Time.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && record.Time.After(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Search copy lines of the audit log matching the filter from r to w, unparseable lines are skipped
+func Search(r io.Reader, w io.Writer, filter Filter) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record Record
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+
+		if !filter.Match(record) {
+			continue
+		}
+
+		_, err := w.Write(append(scanner.Bytes(), '\n'))
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf)
+	records := []Record{
+		{Time: time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC), Node: "node-a", Action: "skip"},
+		{Time: time.Date(2020, 1, 1, 9, 52, 0, 0, time.UTC), Node: "node-a", Action: "recycle", Outcome: "deleted"},
+		{Time: time.Date(2020, 1, 1, 9, 52, 0, 0, time.UTC), Node: "node-b", Action: "skip"},
+	}
+	for _, record := range records {
+		err := logger.Write(record)
+		if err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+	buf.WriteString("not a record\n")
+
+	tests := map[string]struct {
+		Filter   Filter
+		Expected int
+	}{
+		"no filter": {
+			Expected: 3,
+		},
+		"by node": {
+			Filter:   Filter{Node: "node-a"},
+			Expected: 2,
+		},
+		"by node and time": {
+			Filter: Filter{
+				Node:  "node-a",
+				Since: time.Date(2020, 1, 1, 9, 30, 0, 0, time.UTC),
+				Until: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC),
+			},
+			Expected: 1,
+		},
+		"until": {
+			Filter:   Filter{Until: time.Date(2020, 1, 1, 9, 30, 0, 0, time.UTC)},
+			Expected: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := Search(bytes.NewReader(buf.Bytes()), &out, tc.Filter)
+			if err != nil {
+				t.Fatalf("failed to search: %v", err)
+			}
+
+			lines := strings.Count(out.String(), "\n")
+			if lines != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"preemptible-lifecycle-scheduler/config"
+	"strconv"
+	"time"
+)
+
+const (
+	AgeSourceNode     = config.AgeSourceNode
+	AgeSourceLabel    = config.AgeSourceLabel
+	AgeSourceProvider = config.AgeSourceProvider
+
+	// DefaultAgeLabel is read from node labels or annotations, holding instance creation unix timestamp
+	DefaultAgeLabel = lifecyclePrefix + "instance-created-at"
+)
+
+var (
+	// AgeMismatchThreshold is how far node and instance creation time may differ before a warning is logged,
+	// a few minutes are expected between instance creation and kubelet registration.
+	AgeMismatchThreshold  = 10 * time.Minute
+	InstanceLookupTimeout = 30 * time.Second
+)
+
+// InstanceClient look up the cloud instance backing a node, identified by node spec.providerID
+type InstanceClient interface {
+	InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error)
+}
+
+// GetNodeCreatedTime return creation time of the instance backing the node according to AgeSource,
+// node creation timestamp is used when the instance creation time is not available.
+func (c *Client) GetNodeCreatedTime(node corev1.Node) time.Time {
+	nodeCreatedAt := node.CreationTimestamp.Time
+
+	var instanceCreatedAt time.Time
+	var ok bool
+	switch c.AgeSource {
+	case AgeSourceLabel:
+		instanceCreatedAt, ok = c.getLabelCreatedTime(node)
+	case AgeSourceProvider:
+		instanceCreatedAt, ok = c.getInstanceCreatedTime(node)
+	default:
+		return nodeCreatedAt
+	}
+
+	if !ok {
+		c.nodeLogger(&node).Debug("instance creation time is not available, using node creation time")
+		return nodeCreatedAt
+	}
+
+	if d := nodeCreatedAt.Sub(instanceCreatedAt); d > AgeMismatchThreshold || d < -AgeMismatchThreshold {
+		c.nodeLogger(&node).Warnf("node created at %s but instance created at %s",
+			nodeCreatedAt.Format(time.RFC3339), instanceCreatedAt.Format(time.RFC3339))
+	}
+
+	return instanceCreatedAt
+}
+
+func (c *Client) getLabelCreatedTime(node corev1.Node) (time.Time, bool) {
+	label := c.AgeLabel
+	if label == "" {
+		label = DefaultAgeLabel
+	}
+
+	value, ok := node.Labels[label]
+	if !ok {
+		value, ok = node.Annotations[label]
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+
+	ts, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		c.nodeLogger(&node).Warnf("invalid %s: %v", label, err)
+		return time.Time{}, false
+	}
+
+	return time.Unix(ts, 0), true
+}
+
+// getInstanceCreatedTime look up instance creation time once per node object. Recreated instances may keep
+// their provider id, but they always register a new node object. The lock is not held during the lookup, a slow
+// cloud api would block every other node.
+func (c *Client) getInstanceCreatedTime(node corev1.Node) (time.Time, bool) {
+	if c.Instances == nil || node.Spec.ProviderID == "" {
+		return time.Time{}, false
+	}
+
+	key := instanceCreatedTimeKey(node)
+
+	c.mu.Lock()
+	t, ok := c.instanceCreatedTimes[key]
+	c.mu.Unlock()
+	if ok {
+		return t, true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), InstanceLookupTimeout)
+	defer cancel()
+
+	t, err := c.Instances.InstanceCreatedTime(ctx, node.Spec.ProviderID)
+	if err != nil {
+		c.nodeLogger(&node).Errorf("failed to get instance: %v", err)
+		return time.Time{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.instanceCreatedTimes == nil {
+		c.instanceCreatedTimes = make(map[string]time.Time)
+	}
+	c.instanceCreatedTimes[key] = t
+
+	return t, true
+}
+
+// pruneInstanceCreatedTimes forget instance creation time of node objects that are gone
+func (c *Client) pruneInstanceCreatedTimes(nodes []corev1.Node) {
+	existing := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		existing[instanceCreatedTimeKey(node)] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.instanceCreatedTimes {
+		if _, ok := existing[key]; !ok {
+			delete(c.instanceCreatedTimes, key)
+		}
+	}
+}
+
+func instanceCreatedTimeKey(node corev1.Node) string {
+	return string(node.UID) + "/" + node.Spec.ProviderID
+}
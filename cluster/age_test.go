@@ -0,0 +1,142 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+type mockInstanceClient struct {
+	createdAt map[string]time.Time
+	lookups   int
+}
+
+func (c *mockInstanceClient) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	c.lookups++
+	t, ok := c.createdAt[providerID]
+	if !ok {
+		return time.Time{}, errors.New("instance not found")
+	}
+
+	return t, nil
+}
+
+func TestClient_GetNodeCreatedTime(t *testing.T) {
+	nodeCreatedAt := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	instanceCreatedAt := time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		AgeSource   string
+		Labels      map[string]string
+		Annotations map[string]string
+		ProviderID  string
+		Expected    time.Time
+	}{
+		"node": {
+			AgeSource: AgeSourceNode,
+			Labels:    map[string]string{DefaultAgeLabel: "1577858400"},
+			Expected:  nodeCreatedAt,
+		},
+		"label": {
+			AgeSource: AgeSourceLabel,
+			Labels:    map[string]string{DefaultAgeLabel: "1577858400"},
+			Expected:  instanceCreatedAt,
+		},
+		"annotation": {
+			AgeSource:   AgeSourceLabel,
+			Annotations: map[string]string{DefaultAgeLabel: "1577858400"},
+			Expected:    instanceCreatedAt,
+		},
+		"invalid label": {
+			AgeSource: AgeSourceLabel,
+			Labels:    map[string]string{DefaultAgeLabel: "2020-01-01"},
+			Expected:  nodeCreatedAt,
+		},
+		"provider": {
+			AgeSource:  AgeSourceProvider,
+			ProviderID: "gce://project/zone/instance-a",
+			Expected:   instanceCreatedAt,
+		},
+		"provider, instance not found": {
+			AgeSource:  AgeSourceProvider,
+			ProviderID: "gce://project/zone/instance-b",
+			Expected:   nodeCreatedAt,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			instances := &mockInstanceClient{
+				createdAt: map[string]time.Time{"gce://project/zone/instance-a": instanceCreatedAt},
+			}
+			client := &Client{
+				Instances: instances,
+				AgeSource: tc.AgeSource,
+			}
+
+			node := corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "node-a",
+					Labels:            tc.Labels,
+					Annotations:       tc.Annotations,
+					CreationTimestamp: metav1.Time{Time: nodeCreatedAt},
+				},
+				Spec: corev1.NodeSpec{ProviderID: tc.ProviderID},
+			}
+
+			result := client.GetNodeCreatedTime(node)
+			if !result.Equal(tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
+
+func TestClient_GetNodeCreatedTime_Cached(t *testing.T) {
+	instances := &mockInstanceClient{
+		createdAt: map[string]time.Time{"gce://project/zone/instance-a": time.Now()},
+	}
+	client := &Client{
+		Instances: instances,
+		AgeSource: AgeSourceProvider,
+	}
+
+	node := corev1.Node{Spec: corev1.NodeSpec{ProviderID: "gce://project/zone/instance-a"}}
+	client.GetNodeCreatedTime(node)
+	client.GetNodeCreatedTime(node)
+
+	if instances.lookups != 1 {
+		t.Errorf("expected 1 lookup, got %d", instances.lookups)
+	}
+}
+
+func TestClient_PruneInstanceCreatedTimes(t *testing.T) {
+	instances := &mockInstanceClient{
+		createdAt: map[string]time.Time{
+			"gce://project/zone/instance-a": time.Now(),
+			"gce://project/zone/instance-b": time.Now(),
+		},
+	}
+	client := &Client{
+		Instances: instances,
+		AgeSource: AgeSourceProvider,
+	}
+
+	kept := corev1.Node{ObjectMeta: metav1.ObjectMeta{UID: "a"}, Spec: corev1.NodeSpec{ProviderID: "gce://project/zone/instance-a"}}
+	gone := corev1.Node{ObjectMeta: metav1.ObjectMeta{UID: "b"}, Spec: corev1.NodeSpec{ProviderID: "gce://project/zone/instance-b"}}
+	client.GetNodeCreatedTime(kept)
+	client.GetNodeCreatedTime(gone)
+
+	client.pruneInstanceCreatedTimes([]corev1.Node{kept})
+
+	if _, ok := client.instanceCreatedTimes[instanceCreatedTimeKey(gone)]; ok {
+		t.Errorf("expected instance creation time of %s to be forgotten", gone.UID)
+	}
+
+	if _, ok := client.instanceCreatedTimes[instanceCreatedTimeKey(kept)]; !ok {
+		t.Errorf("expected instance creation time of %s to be kept", kept.UID)
+	}
+}
@@ -0,0 +1,172 @@
+package cluster
+
+import (
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+// nodeCapacity is what is left on a node for evicted pods to be scheduled on
+type nodeCapacity struct {
+	node   *corev1.Node
+	cpu    resource.Quantity
+	memory resource.Quantity
+}
+
+// CheckCapacity simulate scheduling the application pods of the node onto the remaining schedulable nodes,
+// using pod cpu and memory requests, node selectors and taints. ErrInsufficientCapacity is returned when
+// a pod does not fit anywhere.
+func (c *Client) CheckCapacity(nodeName string) error {
+	nodeList, err := c.KubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	// a single list of every pod, both to find pods to evict and to know what is used on other nodes
+	podList, err := c.KubeClient.CoreV1().Pods("").List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	candidates := make(map[string]*nodeCapacity)
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if node.Name == nodeName || !isNodeSchedulable(node) {
+			continue
+		}
+
+		candidates[node.Name] = &nodeCapacity{
+			node:   node,
+			cpu:    node.Status.Allocatable.Cpu().DeepCopy(),
+			memory: node.Status.Allocatable.Memory().DeepCopy(),
+		}
+	}
+
+	pods := make([]*corev1.Pod, 0)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		if pod.Spec.NodeName == nodeName {
+			if isApplicationPod(pod) {
+				pods = append(pods, pod)
+			}
+			continue
+		}
+
+		if capacity, ok := candidates[pod.Spec.NodeName]; ok {
+			cpu, memory := podRequests(pod)
+			capacity.cpu.Sub(cpu)
+			capacity.memory.Sub(memory)
+		}
+	}
+
+	// biggest pods first, they are the hardest to place
+	sort.SliceStable(pods, func(i, j int) bool {
+		cpuI, memoryI := podRequests(pods[i])
+		cpuJ, memoryJ := podRequests(pods[j])
+		if cmp := cpuI.Cmp(cpuJ); cmp != 0 {
+			return cmp > 0
+		}
+		return memoryI.Cmp(memoryJ) > 0
+	})
+
+	// sorted so the simulation does not depend on map order
+	nodes := make([]*nodeCapacity, 0)
+	for _, capacity := range candidates {
+		nodes = append(nodes, capacity)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].node.Name < nodes[j].node.Name
+	})
+
+	for _, pod := range pods {
+		cpu, memory := podRequests(pod)
+		fit := false
+		for _, capacity := range nodes {
+			if !podFitsNode(pod, capacity.node) || capacity.cpu.Cmp(cpu) < 0 || capacity.memory.Cmp(memory) < 0 {
+				continue
+			}
+
+			capacity.cpu.Sub(cpu)
+			capacity.memory.Sub(memory)
+			fit = true
+			break
+		}
+
+		if !fit {
+			return fmt.Errorf("%w: pod %s/%s requesting %s cpu and %s memory does not fit", ErrInsufficientCapacity,
+				pod.Namespace, pod.Name, cpu.String(), memory.String())
+		}
+	}
+
+	return nil
+}
+
+// isNodeSchedulable return true for ready nodes accepting new pods, nodes being recycled are left out
+func isNodeSchedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable || HasRecyclingTaint(node) {
+		return false
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// podFitsNode check the pod node selector and that the pod tolerates node taints preventing scheduling
+func podFitsNode(pod *corev1.Pod, node *corev1.Node) bool {
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// podRequests sum container requests, init containers run one at a time so only the biggest one counts
+func podRequests(pod *corev1.Pod) (cpu resource.Quantity, memory resource.Quantity) {
+	for _, container := range pod.Spec.Containers {
+		cpu.Add(*container.Resources.Requests.Cpu())
+		memory.Add(*container.Resources.Requests.Memory())
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		if container.Resources.Requests.Cpu().Cmp(cpu) > 0 {
+			cpu = container.Resources.Requests.Cpu().DeepCopy()
+		}
+		if container.Resources.Requests.Memory().Cmp(memory) > 0 {
+			memory = container.Resources.Requests.Memory().DeepCopy()
+		}
+	}
+
+	return cpu, memory
+}
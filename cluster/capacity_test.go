@@ -0,0 +1,157 @@
+package cluster
+
+import (
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+)
+
+func newCapacityNode(name string, cpu string, memory string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": "default"}},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: newReadyCondition(corev1.ConditionTrue, time.Now()),
+		},
+	}
+}
+
+func newCapacityPod(name string, nodeName string, cpu string, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+	}
+}
+
+func TestClient_CheckCapacity(t *testing.T) {
+	tainted := newCapacityNode("node-b", "4", "8Gi")
+	tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule}}
+
+	selecting := newCapacityPod("pod-a", "node-a", "1", "1Gi")
+	selecting.Spec.NodeSelector = map[string]string{"pool": "highmem"}
+
+	cordoned := newCapacityNode("node-b", "4", "8Gi")
+	cordoned.Spec.Unschedulable = true
+
+	tests := map[string]struct {
+		Objects     []runtime.Object
+		ExpectedErr error
+	}{
+		"fits": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				newCapacityNode("node-b", "4", "8Gi"),
+				newCapacityPod("pod-a", "node-a", "2", "2Gi"),
+				newCapacityPod("pod-b", "node-a", "1", "2Gi"),
+				newCapacityPod("pod-c", "node-b", "1", "1Gi"),
+			},
+		},
+		"spread over nodes": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				newCapacityNode("node-b", "2", "8Gi"),
+				newCapacityNode("node-c", "2", "8Gi"),
+				newCapacityPod("pod-a", "node-a", "2", "1Gi"),
+				newCapacityPod("pod-b", "node-a", "2", "1Gi"),
+			},
+		},
+		"not enough cpu left": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				newCapacityNode("node-b", "4", "8Gi"),
+				newCapacityPod("pod-a", "node-a", "2", "1Gi"),
+				newCapacityPod("pod-b", "node-b", "3", "1Gi"),
+			},
+			ExpectedErr: ErrInsufficientCapacity,
+		},
+		"not enough memory": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				newCapacityNode("node-b", "4", "1Gi"),
+				newCapacityPod("pod-a", "node-a", "1", "2Gi"),
+			},
+			ExpectedErr: ErrInsufficientCapacity,
+		},
+		"node selector not matching": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				newCapacityNode("node-b", "4", "8Gi"),
+				selecting,
+			},
+			ExpectedErr: ErrInsufficientCapacity,
+		},
+		"taint not tolerated": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				tainted,
+				newCapacityPod("pod-a", "node-a", "1", "1Gi"),
+			},
+			ExpectedErr: ErrInsufficientCapacity,
+		},
+		"cordoned node": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				cordoned,
+				newCapacityPod("pod-a", "node-a", "1", "1Gi"),
+			},
+			ExpectedErr: ErrInsufficientCapacity,
+		},
+		"daemonset pods stay": {
+			Objects: []runtime.Object{
+				newCapacityNode("node-a", "4", "8Gi"),
+				newTestPod("ds", "DaemonSet"),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &Client{KubeClient: fake.NewSimpleClientset(tc.Objects...)}
+
+			err := client.CheckCapacity("node-a")
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNode_Deferred(t *testing.T) {
+	node := newCapacityNode("node-a", "4", "8Gi")
+	kubeClient := fake.NewSimpleClientset(node, newCapacityPod("pod-a", "node-a", "1", "1Gi"))
+	client := &Client{
+		KubeClient:    kubeClient,
+		CapacityCheck: true,
+		DeleteTimeout: 100 * time.Millisecond,
+	}
+
+	result, err := client.ProcessNode(node, "test")
+	if !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("expected error %v, got %v", ErrInsufficientCapacity, err)
+	}
+
+	if result.Outcome != OutcomeDeferred || !result.IsRetryable() || result.Cordoned {
+		t.Errorf("expected node deferred, got %+v", result)
+	}
+
+	untouched, _ := kubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+	if GetNodeState(untouched) != "" || HasRecyclingTaint(untouched) {
+		t.Errorf("expected node untouched, got %+v", untouched)
+	}
+}
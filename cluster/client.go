@@ -1,34 +1,103 @@
 package cluster
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
-	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"log"
+	"k8s.io/client-go/tools/record"
 	"os"
 	"path/filepath"
 	"preemptible-lifecycle-scheduler/config"
+	"preemptible-lifecycle-scheduler/hook"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/metrics"
+	"preemptible-lifecycle-scheduler/notify"
+	"preemptible-lifecycle-scheduler/provider"
+	"sync"
 	"time"
 )
 
 var (
-	CheckPodInterval       = 10 * time.Second
+	EvictionRetryInterval  = 10 * time.Second
 	ProcessingNodeInterval = 1 * time.Minute
 )
 
+// Approver block until the step of the node is approved, an error means the step must not run
+type Approver interface {
+	Approve(ctx context.Context, nodeName string, step string) error
+}
+
+// HookRunner call the hooks of a phase with the node and its pods, an error means processing must stop
+type HookRunner interface {
+	Run(ctx context.Context, phase string, nodeName string, pods []corev1.Pod) error
+}
+
+type Notifier interface {
+	Notify(event notify.Event)
+}
+
+// ComputeClient terminate the cloud instance backing a node, identified by node spec.providerID
+type ComputeClient interface {
+	TerminateInstance(ctx context.Context, providerID string) error
+}
+
 type Client struct {
-	KubeClient    *kubernetes.Clientset
+	KubeClient    kubernetes.Interface
+	Recorder      record.EventRecorder
+	Compute       ComputeClient
 	DeleteTimeout time.Duration
-	IncludedPool  string
-	ExcludedPool  string
-	Debug         bool
+	NodeSelectors []labels.Selector
+	IncludedPools []labels.Selector
+	ExcludedPools []labels.Selector
+	PoolLabel     string
+	FailurePolicy string
+	// Notifier receive drain lifecycle events, optional
+	Notifier Notifier
+	// Approver hold every step until an operator approves it, steps run right away when nil
+	Approver Approver
+	// ApprovalTimeout is how long Approver may hold a step, waits are not counted against DeleteTimeout
+	ApprovalTimeout time.Duration
+	// Hooks are called before and after the node is drained, optional
+	Hooks HookRunner
+	// CapacityCheck defer nodes whose pods would not fit on the remaining nodes, or surge when Surge is set
+	CapacityCheck bool
+	// Surge make room for the pods before draining, through Scaler or a balloon pod, disabled when empty.
+	// Every node is surged unless CapacityCheck is set, then only nodes whose pods would not fit.
+	Surge        string
+	SurgeTimeout time.Duration
+	Scaler       Scaler
+	// HealthGateTimeout is how long to wait for the workloads of evicted pods to be available again once
+	// the node is deleted, so the next node is not touched before, disabled when zero
+	HealthGateTimeout time.Duration
+
+	Instances InstanceClient
+	AgeSource string
+	AgeLabel  string
+
+	// InstanceStatus confirm a not ready node was preempted, set to the provider by NewClient. Only nodes marked by
+	// the agent count as preempted when it is nil or the provider has no compute access.
+	InstanceStatus InstanceStatusClient
+
+	// StateNamespace and StateConfigMap locate the ConfigMap scheduler state is persisted in
+	StateNamespace string
+	StateConfigMap string
+
+	mu                   sync.Mutex
+	instanceCreatedTimes map[string]time.Time
+	// healthGate is cancelled to interrupt health gate waits, later waits get a new one
+	healthGate     context.Context
+	stopHealthGate context.CancelFunc
 }
 
-func NewClient(cfg *config.Config) (*Client, error) {
+func NewClient(cfg *config.Config, p provider.Provider) (*Client, error) {
 	var kubernetesConfig *rest.Config
 	var err error
 	if cfg.Environment == config.EnvDevelopment {
@@ -45,218 +114,611 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		return nil, err
 	}
 
+	nodeSelectors := cfg.NodeSelectors
+	if len(nodeSelectors) == 0 {
+		nodeSelectors = p.NodeSelectors()
+	}
+
+	selectors, err := ParseSelectors(nodeSelectors)
+	if err != nil {
+		return nil, err
+	}
+
+	includedPools, err := ParsePoolSelectors(cfg.GetIncludedPools(), p.PoolLabel())
+	if err != nil {
+		return nil, err
+	}
+
+	excludedPools, err := ParsePoolSelectors(cfg.GetExcludedPools(), p.PoolLabel())
+	if err != nil {
+		return nil, err
+	}
+
+	var computeClient ComputeClient
+	if cfg.InstanceAction != "" {
+		computeClient = p
+	}
+
+	var scaler Scaler
+	switch cfg.Surge {
+	case "":
+	case config.SurgeProvider:
+		scaler = p
+	case config.SurgeBalloon:
+	default:
+		return nil, fmt.Errorf("unknown surge mode: %s", cfg.Surge)
+	}
+
+	var instanceClient InstanceClient
+	if cfg.NodeAgeSource == AgeSourceProvider {
+		instanceClient = p
+	}
+
 	return &Client{
 		KubeClient:    clientset,
+		Recorder:      NewEventRecorder(clientset),
+		Compute:       computeClient,
 		DeleteTimeout: time.Duration(cfg.GracefulPeriod) * time.Minute,
-		IncludedPool:  cfg.IncludedPool,
-		ExcludedPool:  cfg.ExcludedPool,
-		Debug:         cfg.Debug,
+		NodeSelectors: selectors,
+		IncludedPools: includedPools,
+		ExcludedPools: excludedPools,
+		PoolLabel:     p.PoolLabel(),
+		FailurePolicy: cfg.FailurePolicy,
+		Instances:     instanceClient,
+		AgeSource:     cfg.NodeAgeSource,
+		AgeLabel:      cfg.NodeAgeLabel,
+		CapacityCheck: cfg.CapacityCheck,
+		Surge:         cfg.Surge,
+		SurgeTimeout:  time.Duration(cfg.SurgeTimeout) * time.Minute,
+		Scaler:        scaler,
+
+		HealthGateTimeout: time.Duration(cfg.HealthGateTimeout) * time.Minute,
+		InstanceStatus:    p,
+
+		StateNamespace: cfg.GetNamespace(),
+		StateConfigMap: cfg.StateConfigMap,
 	}, nil
 }
 
+// Ping check the api server is reachable
+func (c *Client) Ping() error {
+	_, err := c.KubeClient.Discovery().ServerVersion()
+	return err
+}
+
+// GetPreemptibleNodes list nodes matching any of the node selectors, filtered by included and excluded pools.
 func (c *Client) GetPreemptibleNodes() (*corev1.NodeList, error) {
-	log.Printf("scanning nodes")
-	nodes, err := c.KubeClient.CoreV1().Nodes().List(metav1.ListOptions{
-		LabelSelector: "cloud.google.com/gke-preemptible=true",
-	})
-	if err != nil {
-		return nil, err
+	log.WithField(logging.FieldAction, "scan").Debug("scanning nodes")
+	nodes := &corev1.NodeList{
+		Items: make([]corev1.Node, 0),
 	}
 
-	// filter out exception node
-	items := make([]corev1.Node, 0)
-	for _, node := range nodes.Items {
-		if val, ok := node.Labels["cloud.google.com/gke-nodepool"]; ok {
-			if c.IncludedPool != "" {
-				if val != c.IncludedPool {
-					continue
-				}
+	// one list per selector since a single label selector can only express AND
+	found := make(map[string]struct{})
+	for _, selector := range c.NodeSelectors {
+		nodeList, err := c.KubeClient.CoreV1().Nodes().List(metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodeList.Items {
+			if _, ok := found[node.Name]; ok {
+				continue
 			}
+			found[node.Name] = struct{}{}
 
-			if c.ExcludedPool != "" {
-				if val == c.ExcludedPool {
-					continue
-				}
+			// filter out exception node
+			if !IsNodeIncluded(c.IncludedPools, c.ExcludedPools, node.Labels) {
+				continue
 			}
-		}
 
-		items = append(items, node)
+			nodes.Items = append(nodes.Items, node)
+		}
 	}
 
-	nodes.Items = items
-	return nodes, err
+	c.pruneInstanceCreatedTimes(nodes.Items)
+
+	return nodes, nil
 }
 
-func (c *Client) ProcessNode(node *corev1.Node) (err error) {
-	log.Printf("processing node %s", node.Name)
+// ProcessNode cordon, drain and delete the node within DeleteTimeout, reason is why the node is recycled and
+// ends up in node events. When processing times out or fails, the worker is cancelled, the node is rolled back
+// according to FailurePolicy and a *ProcessError is returned along with the result.
+func (c *Client) ProcessNode(node *corev1.Node, reason string) (*Result, error) {
+	startedAt := time.Now()
+	logger := c.nodeLogger(node).WithField(logging.FieldDeadline, logging.Deadline(startedAt.Add(c.DeleteTimeout)))
+	logger.WithField(logging.FieldAction, "process").Info("processing node")
+
+	surge := c.Surge != ""
+	if c.CapacityCheck {
+		err := c.CheckCapacity(node.Name)
+		if err == nil {
+			surge = false
+		} else if surge {
+			logger.WithField(logging.FieldAction, StepCapacity).Infof("surging before drain: %v", err)
+		} else {
+			// nothing was done to the node yet, so there is nothing to roll back
+			logger.WithField(logging.FieldAction, StepCapacity).Warnf("deferring node: %v", err)
+			err = &ProcessError{Node: node.Name, Step: StepCapacity, Err: err}
+			return &Result{
+				Node:     node.Name,
+				Outcome:  OutcomeDeferred,
+				Step:     StepCapacity,
+				Duration: time.Since(startedAt),
+				Err:      err,
+			}, err
+		}
+	}
 
-	var character string
+	// approval waits and surge are not counted against DeleteTimeout
+	ctx := newProcessDeadline(c.DeleteTimeout)
+	defer ctx.stop()
 
-	doneProcessing := make(chan bool)
+	p := &progress{
+		result: Result{Node: node.Name, Step: StepCordon},
+	}
+
+	doneProcessing := make(chan error, 1)
 	go func() {
-		for {
-			if c.Debug {
-				fmt.Println("Press any character to continue unschedule node")
-				_, _ = fmt.Scanln(&character)
-			}
+		doneProcessing <- c.processNode(ctx, node.Name, reason, surge, logger, p)
+	}()
 
-			node, err = c.KubeClient.CoreV1().Nodes().Get(node.Name, metav1.GetOptions{})
-			if err != nil {
-				doneProcessing <- true
-				return
-			}
+	var err error
+	select {
+	case err = <-doneProcessing:
+	case <-ctx.Done():
+		err = ErrProcessTimeout
+	}
 
+	result := p.snapshot()
+	result.Duration = time.Since(startedAt)
+	switch {
+	case err == nil:
+		result.Outcome = OutcomeDeleted
+	case apierrors.IsNotFound(err):
+		// node is gone before we could delete it, most likely preempted already
+		result.Outcome = OutcomeNodeVanished
+		err = nil
+	case errors.Is(err, ErrProcessTimeout) && result.BlockedByPDB:
+		result.Outcome = OutcomeBlockedByPDB
+		err = ErrBlockedByPDB
+	case errors.Is(err, ErrProcessTimeout):
+		result.Outcome = OutcomeTimedOut
+	case errors.Is(err, ErrStepRejected):
+		result.Outcome = OutcomeRejected
+	case errors.Is(err, ErrDrainVetoed):
+		result.Outcome = OutcomeVetoed
+	case errors.Is(err, ErrHookFailed):
+		result.Outcome = OutcomeHookFailed
+	default:
+		result.Outcome = OutcomeFailed
+	}
+
+	if err != nil {
+		err = &ProcessError{Node: node.Name, Step: result.Step, Err: err}
+		result.Err = err
+		logger.WithFields(log.Fields{
+			logging.FieldAction: result.Step,
+			logging.FieldState:  result.Outcome,
+		}).Errorf("failed processing node: %v", err)
+		// pods are gone once post-drain hooks run, the node is not opened to them again
+		c.RollbackNode(node.Name, err, result.Outcome == OutcomeHookFailed && result.Step == StepPostDrain)
+		if result.Outcome != OutcomeRejected {
+			c.notify(notify.Event{
+				Type:          notify.EventDrainFailed,
+				Node:          node.Name,
+				Message:       err.Error(),
+				Step:          result.Step,
+				Outcome:       string(result.Outcome),
+				PodsEvicted:   result.PodsEvicted,
+				PodsRemaining: result.PodsRemaining,
+			})
+		}
+		return &result, err
+	}
+
+	if result.Outcome == OutcomeDeleted {
+		if workloads := p.getWorkloads(); len(workloads) > 0 {
+			result.WorkloadsUnavailable = len(c.waitForWorkloads(c.healthGateContext(), node.Name, workloads, logger))
+		}
+
+		c.notify(notify.Event{
+			Type:        notify.EventDrainCompleted,
+			Node:        node.Name,
+			Message:     fmt.Sprintf("node deleted in %s: %s", result.Duration.Round(time.Second), reason),
+			Step:        result.Step,
+			Outcome:     string(result.Outcome),
+			PodsEvicted: result.PodsEvicted,
+		})
+	}
+
+	logger.WithField(logging.FieldState, result.Outcome).Infof("done processing node in %s", result.Duration)
+	return &result, nil
+}
+
+func (c *Client) processNode(ctx *processDeadline, nodeName string, reason string, surge bool, logger *log.Entry, p *progress) error {
+	var node *corev1.Node
+	err := c.approve(ctx, nodeName, StepCordon)
+	if err != nil {
+		return err
+	}
+
+	for {
+		node, err = c.KubeClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+		if err == nil {
 			err = c.UnScheduleNode(node)
-			if err != nil {
-				log.Printf("error unschedule node: %s, err :%v", node.Name, err)
-				time.Sleep(ProcessingNodeInterval)
-				continue
-			}
-			break
 		}
 
-		for {
-			if c.Debug {
-				fmt.Println("Press any character to continue delete pods")
-				_, _ = fmt.Scanln(&character)
-			}
+		if apierrors.IsNotFound(err) {
+			return err
+		}
 
-			err = c.DeletePods(node.Name)
-			if err != nil {
-				log.Printf("error delete pods: %s, err :%v", node.Name, err)
-				time.Sleep(ProcessingNodeInterval)
-				continue
+		if err != nil {
+			logger.WithField(logging.FieldAction, StepCordon).Warnf("error unschedule node: %v", err)
+			if !sleep(ctx, ProcessingNodeInterval) {
+				return ErrProcessTimeout
 			}
-			break
+			continue
 		}
+		break
+	}
 
-		for {
-			if c.Debug {
-				fmt.Println("Press any character to continue delete node")
-				_, _ = fmt.Scanln(&character)
-			}
+	c.nodeEvent(node.Name, corev1.EventTypeNormal, EventReasonCordoned, "Cordoned for recycling: %s", reason)
+	p.update(func(result *Result) {
+		result.Cordoned = true
+		result.Step = StepDrain
+	})
+
+	if surge {
+		p.update(func(result *Result) {
+			result.Step = StepSurge
+		})
+		// the new capacity is waited for before the drain time starts
+		err = ctx.hold(func(holdCtx context.Context) error {
+			return c.surgeNode(holdCtx, node, logger)
+		})
+		if ctx.Err() != nil {
+			return ErrProcessTimeout
+		}
+		if err != nil {
+			return err
+		}
+		p.update(func(result *Result) {
+			result.Step = StepDrain
+		})
+	}
+
+	err = c.approve(ctx, nodeName, StepDrain)
+	if err != nil {
+		return err
+	}
 
-			err = c.DeleteNode(node.Name)
+	p.update(func(result *Result) {
+		result.Step = StepPreDrain
+	})
+	pods, err := c.runHooks(ctx, hook.PhasePreDrain, node.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	if c.HealthGateTimeout > 0 {
+		if pods == nil {
+			pods, err = c.GetPods(node.Name)
 			if err != nil {
-				log.Printf("error delete node: %s, err :%v", node.Name, err)
-				time.Sleep(ProcessingNodeInterval)
-				continue
+				return err
 			}
+		}
+		p.setWorkloads(c.availableWorkloads(c.getWorkloads(pods), logger))
+	}
+	p.update(func(result *Result) {
+		result.Step = StepDrain
+	})
 
-			if c.Debug {
-				fmt.Println("Press any character to continue scheduling")
-				_, _ = fmt.Scanln(&character)
-			}
+	// the drain is announced once it was approved and pre-drain hooks let it go
+	metrics.NodeTransitions.WithLabelValues(metrics.TransitionDraining).Inc()
+	drainStartedAt := time.Now()
+	deadline, _ := ctx.Deadline()
+	c.nodeEvent(node.Name, corev1.EventTypeNormal, EventReasonDraining, "Draining node before %s: %s",
+		deadline.UTC().Format(time.RFC3339), reason)
+	c.notify(notify.Event{
+		Type:    notify.EventDrainStarted,
+		Node:    node.Name,
+		Message: fmt.Sprintf("draining node before %s: %s", deadline.UTC().Format(time.RFC3339), reason),
+		Step:    StepDrain,
+	})
 
-			doneProcessing <- true
-			return
+	for {
+		err = c.DeletePods(ctx, node.Name, func(drain DrainProgress) {
+			logProgress(logger, drain)
+			p.update(func(result *Result) {
+				result.PodsEvicted = drain.Evicted
+				result.PodsRemaining = drain.Remaining
+				result.BlockedByPDB = drain.BlockedByPDB
+			})
+		})
+		if err != nil {
+			logger.WithField(logging.FieldAction, StepDrain).Warnf("error delete pods: %v", err)
+			if !sleep(ctx, ProcessingNodeInterval) {
+				return ErrProcessTimeout
+			}
+			continue
 		}
-	}()
-
-	select {
-	case <-doneProcessing:
-		log.Println("done processing node")
 		break
-	case <-time.After(c.DeleteTimeout):
-		log.Println("timeout processing node")
-		return nil
 	}
+	metrics.DrainDuration.Observe(time.Since(drainStartedAt).Seconds())
 
-	return nil
-}
-
-func (c *Client) UnScheduleNode(node *corev1.Node) error {
-	log.Printf("unschedule node %s", node.Name)
-	node.Spec.Unschedulable = true
-	_, err := c.KubeClient.CoreV1().Nodes().Update(node)
-	return err
-}
+	p.update(func(result *Result) {
+		result.Step = StepPostDrain
+	})
+	_, err = c.runHooks(ctx, hook.PhasePostDrain, node.Name, pods)
+	if err != nil {
+		return err
+	}
 
-func (c *Client) DeletePods(nodeName string) error {
-	log.Printf("deleting pods in node %s", nodeName)
-	pods, err := c.GetPods(nodeName)
+	p.update(func(result *Result) {
+		result.Step = StepDelete
+	})
+	err = c.approve(ctx, nodeName, StepDelete)
 	if err != nil {
 		return err
 	}
 
-	for _, pod := range pods {
-		// TODO: try to check the grace period in delete option
-		err = c.KubeClient.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{})
+	for {
+		err = c.MarkNodeDeleting(node.Name)
+		if apierrors.IsNotFound(err) {
+			return err
+		}
+
 		if err != nil {
-			log.Printf("failed to delete pod %s: %v", pod.Name, err)
+			logger.WithField(logging.FieldAction, StepDelete).Warnf("error mark node deleting: %v", err)
+			if !sleep(ctx, ProcessingNodeInterval) {
+				return ErrProcessTimeout
+			}
 			continue
 		}
+		break
 	}
 
-	doneDeleting := make(chan bool)
-	go func() {
-		// check whether all pods have been terminated
+	// the instance goes before the node, a kubelet still running could register the node again once it is
+	// deleted. Until then the node is still there, so a failed termination can be retried.
+	terminated := false
+	if c.Compute != nil {
+		p.update(func(result *Result) {
+			result.Step = StepTerminate
+		})
 		for {
-			pods, err := c.GetPods(nodeName)
+			err = c.Compute.TerminateInstance(ctx, node.Spec.ProviderID)
 			if err != nil {
-				log.Printf("error get pods from node: %s, err: %v", nodeName, err)
-				time.Sleep(CheckPodInterval)
+				logger.WithField(logging.FieldAction, StepTerminate).Warnf("error terminate instance %s: %v", node.Spec.ProviderID, err)
+				if !sleep(ctx, ProcessingNodeInterval) {
+					return ErrProcessTimeout
+				}
 				continue
 			}
 
-			if len(pods) == 0 {
-				doneDeleting <- true
-				return
-			}
+			terminated = true
+			p.update(func(result *Result) {
+				result.InstanceTerminated = true
+				result.Step = StepDelete
+			})
+			break
+		}
+	}
 
-			// wait for pod to be deleted
-			time.Sleep(CheckPodInterval)
+	c.nodeEvent(node.Name, corev1.EventTypeNormal, EventReasonDeleting, "Deleting node: %s", reason)
+	for {
+		err = c.DeleteNode(node.Name)
+		// the node of a terminated instance may be removed by the cloud controller before we get to it
+		if apierrors.IsNotFound(err) && !terminated {
+			return err
 		}
-	}()
 
-	select {
-	case <-doneDeleting:
-		log.Println("done deleting")
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.WithField(logging.FieldAction, StepDelete).Warnf("error delete node: %v", err)
+			if !sleep(ctx, ProcessingNodeInterval) {
+				return ErrProcessTimeout
+			}
+			continue
+		}
+
+		p.update(func(result *Result) {
+			result.Deleted = true
+		})
+		metrics.NodeTransitions.WithLabelValues(metrics.TransitionDeleted).Inc()
 		break
-	case <-time.After(c.DeleteTimeout):
-		log.Println("timeout deleting node")
+	}
+
+	return nil
+}
+
+func (c *Client) notify(event notify.Event) {
+	if c.Notifier == nil {
+		return
+	}
+
+	c.Notifier.Notify(event)
+}
+
+// runHooks call the hooks of the phase when hooks are set. Pre-drain hooks get the pods currently on the node,
+// which are returned to be handed to post-drain hooks once they are evicted. A veto of a pre-drain hook returns
+// ErrDrainVetoed, any other failure ErrHookFailed.
+func (c *Client) runHooks(ctx context.Context, phase string, nodeName string, pods []corev1.Pod) ([]corev1.Pod, error) {
+	if c.Hooks == nil {
+		return pods, nil
+	}
+
+	var err error
+	if pods == nil {
+		pods, err = c.GetPods(nodeName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.Hooks.Run(ctx, phase, nodeName, pods)
+	if ctx.Err() != nil {
+		return pods, ErrProcessTimeout
+	}
+	// only pre-drain hooks can veto, anything else is a hook that could not do its job
+	if errors.Is(err, hook.ErrVetoed) && phase == hook.PhasePreDrain {
+		c.nodeEvent(nodeName, corev1.EventTypeWarning, EventReasonDrainVetoed, "Stopped by %s hook: %v", phase, err)
+		return pods, fmt.Errorf("%w: %v", ErrDrainVetoed, err)
+	}
+	if err != nil {
+		c.nodeEvent(nodeName, corev1.EventTypeWarning, EventReasonHookFailed, "Stopped by failed %s hook: %v", phase, err)
+		return pods, fmt.Errorf("%w: %v", ErrHookFailed, err)
+	}
+
+	return pods, nil
+}
+
+// MaxProcessDuration return how long ProcessNode may take at most, DeleteTimeout along with the waits held
+// out of it
+func (c *Client) MaxProcessDuration() time.Duration {
+	d := c.DeleteTimeout
+	if c.Approver != nil {
+		// cordon, drain and delete are approved
+		d += 3 * c.ApprovalTimeout
+	}
+	if c.Surge != "" {
+		d += c.surgeTimeout()
+	}
+	// workloads are waited for once the node is deleted
+	d += c.HealthGateTimeout
+
+	return d
+}
+
+// approve wait for the step to be approved when an approver is set, the deadline is held meanwhile
+func (c *Client) approve(ctx *processDeadline, nodeName string, step string) error {
+	if c.Approver == nil {
 		return nil
 	}
 
+	err := ctx.hold(func(holdCtx context.Context) error {
+		return c.Approver.Approve(holdCtx, nodeName, step)
+	})
+	if ctx.Err() != nil {
+		return ErrProcessTimeout
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStepRejected, err)
+	}
+
+	return nil
+}
+
+// nodeLogger return a log entry carrying node name and pool
+func (c *Client) nodeLogger(node *corev1.Node) *log.Entry {
+	return logging.Node(node.Name, node.Labels[c.PoolLabel])
+}
+
+// sleep wait for the duration, return false when the context is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// UnScheduleNode taint node with NoSchedule recycling taint and mark it as draining.
+func (c *Client) UnScheduleNode(node *corev1.Node) error {
+	c.nodeLogger(node).WithFields(log.Fields{
+		logging.FieldAction: StepCordon,
+		logging.FieldState:  StateDraining,
+	}).Info("unschedule node")
+	err := c.cordonNode(node, StateDraining)
+	if err != nil {
+		return err
+	}
+
+	metrics.NodeTransitions.WithLabelValues(metrics.TransitionCordoned).Inc()
 	return nil
 }
 
+func (c *Client) cordonNode(node *corev1.Node, state string) error {
+	operations := make([]patchOperation, 0)
+	if !HasRecyclingTaint(node) {
+		taints := append(append([]corev1.Taint{}, node.Spec.Taints...), corev1.Taint{
+			Key:    TaintKeyRecycling,
+			Value:  "true",
+			Effect: corev1.TaintEffectNoSchedule,
+		})
+		operations = append(operations, taintsPatch(node, taints)...)
+	}
+
+	operations = append(operations, statePatch(node, state, time.Now(), nil)...)
+	_, err := c.patchNode(node.Name, operations)
+	return err
+}
+
 // Get all application pods, filtered out pod from kube-system namespace and DaemonSet.
 func (c *Client) GetPods(nodeName string) (pods []corev1.Pod, err error) {
+	pods, _, err = c.listPods(nodeName)
+	return
+}
+
+// listPods also return list resource version, so the list can be followed by a watch
+func (c *Client) listPods(nodeName string) (pods []corev1.Pod, resourceVersion string, err error) {
 	pods = make([]corev1.Pod, 0)
 	podList, err := c.KubeClient.CoreV1().Pods("").List(metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("spec.nodeName=%s,metadata.namespace!=kube-system", nodeName),
+		FieldSelector: podFieldSelector(nodeName),
 	})
 	if err != nil {
 		return
 	}
 
 	for _, pod := range podList.Items {
-		// filter out pod from DaemonSet
-		isDaemonSet := false
-		for _, owner := range pod.ObjectMeta.OwnerReferences {
-			if owner.Kind == "DaemonSet" {
-				isDaemonSet = true
-				break
-			}
-		}
-
-		if isDaemonSet {
+		if !isApplicationPod(&pod) {
 			continue
 		}
 
 		pods = append(pods, pod)
 	}
 
-	return
+	return pods, podList.ResourceVersion, nil
+}
+
+func podFieldSelector(nodeName string) string {
+	return fmt.Sprintf("spec.nodeName=%s,metadata.namespace!=kube-system", nodeName)
+}
+
+func isApplicationPod(pod *corev1.Pod) bool {
+	if pod.Namespace == metav1.NamespaceSystem {
+		return false
+	}
+
+	// filter out pod from DaemonSet
+	for _, owner := range pod.ObjectMeta.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MarkNodeDeleting set deleting state on the latest version of the node
+func (c *Client) MarkNodeDeleting(nodeName string) error {
+	node, err := c.KubeClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if GetNodeState(node) == StateDeleting {
+		return nil
+	}
+
+	_, err = c.SetNodeState(node, StateDeleting)
+	return err
 }
 
 func (c *Client) DeleteNode(nodeName string) error {
 	// TODO: try to check the grace period in delete option
-	log.Printf("deleting node %s", nodeName)
+	logging.Node(nodeName, "").WithField(logging.FieldAction, StepDelete).Info("deleting node")
 	return c.KubeClient.CoreV1().Nodes().Delete(nodeName, &metav1.DeleteOptions{})
 }
-
-func (c *Client) GetNodeCreatedTime(node corev1.Node) time.Time {
-	return node.CreationTimestamp.Time
-}
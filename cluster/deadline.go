@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// processDeadline is a context cancelled once DeleteTimeout of work on a node is spent. The clock is stopped
+// while waiting for an operator approval or surge capacity, such waits have their own timeout and must not eat
+// into drain time.
+type processDeadline struct {
+	context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+}
+
+func newProcessDeadline(timeout time.Duration) *processDeadline {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &processDeadline{
+		Context:  ctx,
+		cancel:   cancel,
+		deadline: time.Now().Add(timeout),
+	}
+	d.timer = time.AfterFunc(timeout, cancel)
+
+	return d
+}
+
+// Deadline return when the context is cancelled unless the clock is stopped again
+func (d *processDeadline) Deadline() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.deadline, true
+}
+
+// hold stop the clock while f runs, the time it takes is added to the deadline. f gets a context without
+// deadline, which is only cancelled when the deadline was already over or the processing is stopped.
+func (d *processDeadline) hold(f func(ctx context.Context) error) error {
+	d.mu.Lock()
+	stopped := d.timer.Stop()
+	d.mu.Unlock()
+
+	startedAt := time.Now()
+	err := f(d.Context)
+
+	if stopped {
+		d.mu.Lock()
+		d.deadline = d.deadline.Add(time.Since(startedAt))
+		d.timer.Reset(time.Until(d.deadline))
+		d.mu.Unlock()
+	}
+
+	return err
+}
+
+// stop cancel the context right away
+func (d *processDeadline) stop() {
+	d.timer.Stop()
+	d.cancel()
+}
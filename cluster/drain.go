@@ -0,0 +1,220 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/metrics"
+	"time"
+)
+
+type PodEvent string
+
+const (
+	PodEvicted         PodEvent = "evicted"
+	PodEvictionBlocked PodEvent = "eviction-blocked"
+	PodEvictionFailed  PodEvent = "eviction-failed"
+	PodTerminated      PodEvent = "terminated"
+)
+
+// drain track application pods of a node until all of them are terminated
+type drain struct {
+	client     *Client
+	nodeName   string
+	onProgress func(DrainProgress)
+	logger     *log.Entry
+
+	remaining map[types.UID]corev1.Pod
+	evicted   map[types.UID]struct{}
+	blocked   map[types.UID]struct{}
+}
+
+// DeletePods evict application pods in the node and wait until they are terminated. Termination is tracked
+// with a pod watch on the node, evictions refused by a PodDisruptionBudget are retried every EvictionRetryInterval.
+// onProgress is called for every pod event. ErrDrainTimeout is returned when pods are still running once
+// the context is done.
+func (c *Client) DeletePods(ctx context.Context, nodeName string, onProgress func(DrainProgress)) error {
+	logger := logging.Node(nodeName, "").WithField(logging.FieldAction, StepDrain)
+	logger.Info("deleting pods in node")
+	d := &drain{
+		client:     c,
+		nodeName:   nodeName,
+		onProgress: onProgress,
+		logger:     logger,
+		remaining:  make(map[types.UID]corev1.Pod),
+		evicted:    make(map[types.UID]struct{}),
+		blocked:    make(map[types.UID]struct{}),
+	}
+
+	watcher, err := d.sync()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		watcher.Stop()
+	}()
+
+	d.evictAll()
+
+	retry := time.NewTicker(EvictionRetryInterval)
+	defer retry.Stop()
+
+	for len(d.remaining) > 0 {
+		select {
+		case <-ctx.Done():
+			logger.Warnf("timeout deleting pods in node, %d pods remaining", len(d.remaining))
+			c.nodeEvent(nodeName, corev1.EventTypeWarning, EventReasonDrainTimeout, "Drain timed out, %d pods remaining", len(d.remaining))
+			return ErrDrainTimeout
+
+		case event, ok := <-watcher.ResultChan():
+			if !ok || event.Type == watch.Error {
+				// watch expired or was closed by the api server, start over from a fresh list
+				watcher.Stop()
+				watcher, err = d.sync()
+				if err != nil {
+					return err
+				}
+				d.evictAll()
+				continue
+			}
+
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok || !isApplicationPod(pod) {
+				continue
+			}
+
+			switch event.Type {
+			case watch.Deleted:
+				d.terminated(pod.UID)
+			case watch.Added:
+				// pod scheduled to the node before the taint took effect
+				if _, ok := d.remaining[pod.UID]; !ok {
+					d.remaining[pod.UID] = *pod
+					d.evict(*pod)
+				}
+			}
+
+		case <-retry.C:
+			d.evictAll()
+		}
+	}
+
+	logger.Info("done deleting pods in node")
+	return nil
+}
+
+// sync list pods in the node and start watching from the list resource version.
+// Pods that are no longer listed are reported as terminated.
+func (d *drain) sync() (watch.Interface, error) {
+	pods, resourceVersion, err := d.client.listPods(d.nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	watcher, err := d.client.KubeClient.CoreV1().Pods("").Watch(metav1.ListOptions{
+		FieldSelector:   podFieldSelector(d.nodeName),
+		ResourceVersion: resourceVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch pods: %v", err)
+	}
+
+	listed := make(map[types.UID]struct{})
+	for _, pod := range pods {
+		listed[pod.UID] = struct{}{}
+		if _, ok := d.remaining[pod.UID]; !ok {
+			d.remaining[pod.UID] = pod
+		}
+	}
+
+	for uid := range d.remaining {
+		if _, ok := listed[uid]; !ok {
+			d.terminated(uid)
+		}
+	}
+
+	return watcher, nil
+}
+
+// evictAll evict remaining pods that have not been evicted yet, including the ones blocked before
+func (d *drain) evictAll() {
+	for _, pod := range d.remaining {
+		d.evict(pod)
+	}
+}
+
+func (d *drain) evict(pod corev1.Pod) {
+	if _, ok := d.evicted[pod.UID]; ok {
+		return
+	}
+
+	err := d.client.EvictPod(pod)
+	switch {
+	case apierrors.IsTooManyRequests(err):
+		d.logger.Warnf("eviction of pod %s/%s blocked by disruption budget", pod.Namespace, pod.Name)
+		metrics.EvictionFailures.WithLabelValues(metrics.ReasonBlockedByPDB).Inc()
+		// retried every EvictionRetryInterval, only the first refusal is recorded
+		if _, ok := d.blocked[pod.UID]; !ok {
+			d.client.podEvent(&pod, corev1.EventTypeWarning, EventReasonEvictionBlocked,
+				"Eviction from node %s blocked by disruption budget, retrying", d.nodeName)
+		}
+		d.blocked[pod.UID] = struct{}{}
+		d.report(pod, PodEvictionBlocked)
+	case err != nil && !apierrors.IsNotFound(err):
+		d.logger.Errorf("failed to evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		metrics.EvictionFailures.WithLabelValues(metrics.ReasonError).Inc()
+		d.client.podEvent(&pod, corev1.EventTypeWarning, EventReasonEvictionFailed, "Failed to evict from node %s: %v", d.nodeName, err)
+		d.report(pod, PodEvictionFailed)
+	default:
+		delete(d.blocked, pod.UID)
+		d.evicted[pod.UID] = struct{}{}
+		metrics.PodsEvicted.Inc()
+		d.client.podEvent(&pod, corev1.EventTypeNormal, EventReasonEvicted, "Evicted from node %s being recycled", d.nodeName)
+		d.client.nodeEvent(d.nodeName, corev1.EventTypeNormal, EventReasonEvicted, "Evicted pod %s/%s", pod.Namespace, pod.Name)
+		d.report(pod, PodEvicted)
+	}
+}
+
+func (d *drain) terminated(uid types.UID) {
+	pod, ok := d.remaining[uid]
+	if !ok {
+		return
+	}
+
+	delete(d.remaining, uid)
+	delete(d.blocked, uid)
+	d.report(pod, PodTerminated)
+}
+
+func (d *drain) report(pod corev1.Pod, event PodEvent) {
+	d.onProgress(DrainProgress{
+		Pod:          fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+		Event:        event,
+		Evicted:      len(d.evicted),
+		Remaining:    len(d.remaining),
+		BlockedByPDB: len(d.blocked) > 0,
+	})
+}
+
+// logProgress log a pod event of a drain along with how many pods are left
+func logProgress(logger *log.Entry, progress DrainProgress) {
+	logger.WithField(logging.FieldAction, StepDrain).Infof("pod %s %s, %d remaining", progress.Pod, progress.Event, progress.Remaining)
+}
+
+// EvictPod delete the pod through eviction API so disruption budgets are respected
+func (c *Client) EvictPod(pod corev1.Pod) error {
+	// TODO: try to check the grace period in delete option
+	return c.KubeClient.PolicyV1beta1().Evictions(pod.Namespace).Evict(&policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+	})
+}
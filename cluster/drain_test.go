@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func newTestPod(name string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: "node-a"},
+	}
+	if owner != "" {
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner"}}
+	}
+
+	return pod
+}
+
+func TestClient_DeletePods(t *testing.T) {
+	tests := map[string]struct {
+		Pods           []runtime.Object
+		Terminate      bool
+		ExpectedErr    error
+		ExpectedEvents map[PodEvent]int
+	}{
+		"all terminated": {
+			Pods:        []runtime.Object{newTestPod("pod-a", "ReplicaSet"), newTestPod("pod-b", ""), newTestPod("ds", "DaemonSet")},
+			Terminate:   true,
+			ExpectedErr: nil,
+			ExpectedEvents: map[PodEvent]int{
+				PodEvicted:    2,
+				PodTerminated: 2,
+			},
+		},
+		"no pods": {
+			Pods:           []runtime.Object{newTestPod("ds", "DaemonSet")},
+			ExpectedErr:    nil,
+			ExpectedEvents: map[PodEvent]int{},
+		},
+		"never terminated": {
+			Pods:        []runtime.Object{newTestPod("pod-a", "ReplicaSet")},
+			Terminate:   false,
+			ExpectedErr: ErrDrainTimeout,
+			ExpectedEvents: map[PodEvent]int{
+				PodEvicted: 1,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(tc.Pods...)
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if !tc.Terminate {
+					return true, nil, nil
+				}
+
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				return true, nil, kubeClient.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			events := make(map[PodEvent]int)
+			client := &Client{KubeClient: kubeClient}
+			err := client.DeletePods(ctx, "node-a", func(progress DrainProgress) {
+				events[progress.Event]++
+			})
+			if err != tc.ExpectedErr {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if len(events) != len(tc.ExpectedEvents) {
+				t.Errorf("expected %v, got %v", tc.ExpectedEvents, events)
+			}
+			for event, count := range tc.ExpectedEvents {
+				if events[event] != count {
+					t.Errorf("expected %v, got %v", tc.ExpectedEvents, events)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	StepCapacity   = "capacity-check"
+	StepCordon     = "cordon"
+	StepSurge      = "surge"
+	StepPreDrain   = "pre-drain-hook"
+	StepDrain      = "drain"
+	StepPostDrain  = "post-drain-hook"
+	StepDelete     = "delete"
+	StepTerminate  = "terminate-instance"
+	StepHealthGate = "health-gate"
+)
+
+var (
+	ErrProcessTimeout = errors.New("timeout processing node")
+	ErrDrainTimeout   = errors.New("timeout waiting pods to be terminated")
+	ErrBlockedByPDB   = errors.New("eviction blocked by pod disruption budget")
+	ErrStepRejected   = errors.New("step was not approved")
+	ErrDrainVetoed    = errors.New("drain was vetoed by hook")
+	ErrHookFailed     = errors.New("hook failed")
+
+	ErrInsufficientCapacity = errors.New("remaining nodes can not absorb evicted pods")
+	ErrSurgeTimeout         = errors.New("timeout waiting for replacement capacity")
+)
+
+// ProcessError is returned by ProcessNode when a node could not be recycled,
+// Step is the last step the node has reached.
+type ProcessError struct {
+	Node string
+	Step string
+	Err  error
+}
+
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("node %s failed at %s step: %v", e.Node, e.Step, e.Err)
+}
+
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	EventComponent = "preemptible-lifecycle-scheduler"
+
+	EventReasonCordoned        = "Cordoned"
+	EventReasonSurge           = "Surge"
+	EventReasonDraining        = "Draining"
+	EventReasonEvicted         = "Evicted"
+	EventReasonEvictionBlocked = "EvictionBlocked"
+	EventReasonEvictionFailed  = "EvictionFailed"
+	EventReasonDrainTimeout    = "DrainTimeout"
+	EventReasonDrainVetoed     = "DrainVetoed"
+	EventReasonHookFailed      = "HookFailed"
+	EventReasonDeleting        = "Deleting"
+)
+
+// NewEventRecorder return a recorder writing events to the api server
+func NewEventRecorder(kubeClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: kubeClient.CoreV1().Events(""),
+	})
+
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: EventComponent})
+}
+
+// nodeReference refer to a node the way kubelet does, so events show up in kubectl describe node
+func nodeReference(nodeName string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind: "Node",
+		Name: nodeName,
+		UID:  types.UID(nodeName),
+	}
+}
+
+// nodeEvent record an event on the node, it is a no-op when the client has no recorder
+func (c *Client) nodeEvent(nodeName string, eventType string, reason string, messageFmt string, args ...interface{}) {
+	if c.Recorder == nil {
+		return
+	}
+
+	c.Recorder.Eventf(nodeReference(nodeName), eventType, reason, messageFmt, args...)
+}
+
+// podEvent record an event on the pod, it is a no-op when the client has no recorder
+func (c *Client) podEvent(pod *corev1.Pod, eventType string, reason string, messageFmt string, args ...interface{}) {
+	if c.Recorder == nil {
+		return
+	}
+
+	c.Recorder.Eventf(pod, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_ProcessNode_Events(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	kubeClient := fake.NewSimpleClientset(node, newTestPod("pod-a", "ReplicaSet"))
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		return true, nil, kubeClient.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+	})
+
+	recorder := record.NewFakeRecorder(10)
+	client := &Client{
+		KubeClient:    kubeClient,
+		Recorder:      recorder,
+		DeleteTimeout: 100 * time.Millisecond,
+	}
+
+	_, err := client.ProcessNode(node, "expiring in 10m")
+	if err != nil {
+		t.Fatalf("failed to process node: %v", err)
+	}
+	close(recorder.Events)
+
+	events := make([]string, 0)
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+
+	expected := []string{
+		"Normal Cordoned Cordoned for recycling: expiring in 10m",
+		"Normal Draining",
+		"Normal Evicted Evicted from node node-a",
+		"Normal Evicted Evicted pod default/pod-a",
+		"Normal Deleting Deleting node: expiring in 10m",
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+
+	for i := range expected {
+		if !strings.HasPrefix(events[i], expected[i]) {
+			t.Errorf("expected %v, got %v", expected[i], events[i])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package cluster
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"preemptible-lifecycle-scheduler/config"
+	"preemptible-lifecycle-scheduler/logging"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	lifecyclePrefix = "preemptible-lifecycle-scheduler/"
+
+	// TaintKeyRecycling is applied with NoSchedule effect to nodes being recycled by the scheduler,
+	// so it can be told apart from a manual cordon.
+	TaintKeyRecycling = lifecyclePrefix + "recycling"
+	LabelState        = lifecyclePrefix + "state"
+	AnnotationFailure = lifecyclePrefix + "failure"
+
+	StateDraining = "draining"
+	StateDeleting = "deleting"
+	StateFailed   = "failed"
+)
+
+// GetStateAnnotation return annotation key holding the time node entered the state
+func GetStateAnnotation(state string) string {
+	return lifecyclePrefix + state + "-at"
+}
+
+func GetNodeState(node *corev1.Node) string {
+	return node.Labels[LabelState]
+}
+
+func HasRecyclingTaint(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == TaintKeyRecycling {
+			return true
+		}
+	}
+
+	return false
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// escape json pointer as described in RFC 6901
+func escapeJSONPointer(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+// mapPatch set keys in labels or annotations, creating the map when it does not exist yet
+func mapPatch(path string, m map[string]string, values map[string]string) []patchOperation {
+	if m == nil {
+		return []patchOperation{{Op: "add", Path: path, Value: values}}
+	}
+
+	keys := make([]string, 0)
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	operations := make([]patchOperation, 0)
+	for _, key := range keys {
+		operations = append(operations, patchOperation{Op: "add", Path: path + "/" + escapeJSONPointer(key), Value: values[key]})
+	}
+
+	return operations
+}
+
+// taintsPatch replace node taints, guarded by a test operation so taints changed
+// by another controller in the meantime are not overwritten.
+func taintsPatch(node *corev1.Node, taints []corev1.Taint) []patchOperation {
+	if len(node.Spec.Taints) == 0 {
+		return []patchOperation{{Op: "add", Path: "/spec/taints", Value: taints}}
+	}
+
+	return []patchOperation{
+		{Op: "test", Path: "/spec/taints", Value: node.Spec.Taints},
+		{Op: "replace", Path: "/spec/taints", Value: taints},
+	}
+}
+
+func statePatch(node *corev1.Node, state string, t time.Time, annotations map[string]string) []patchOperation {
+	values := map[string]string{
+		GetStateAnnotation(state): t.UTC().Format(time.RFC3339),
+	}
+	for key, value := range annotations {
+		values[key] = value
+	}
+
+	operations := mapPatch("/metadata/labels", node.Labels, map[string]string{LabelState: state})
+	return append(operations, mapPatch("/metadata/annotations", node.Annotations, values)...)
+}
+
+func (c *Client) patchNode(nodeName string, operations []patchOperation) (*corev1.Node, error) {
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.KubeClient.CoreV1().Nodes().Patch(nodeName, types.JSONPatchType, data)
+}
+
+// SetNodeState label node with lifecycle state and annotate the time it entered the state
+func (c *Client) SetNodeState(node *corev1.Node, state string) (*corev1.Node, error) {
+	return c.patchNode(node.Name, statePatch(node, state, time.Now(), nil))
+}
+
+// RollbackNode mark node as failed with the cause, the recycling taint is removed
+// unless FailurePolicy asks to keep the node cordoned for inspection or keepCordoned is set.
+func (c *Client) RollbackNode(nodeName string, cause error, keepCordoned bool) {
+	logger := logging.Node(nodeName, "").WithField(logging.FieldAction, "rollback")
+	node, err := c.KubeClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logger.Info("node is already gone, nothing to roll back")
+		return
+	}
+	if err != nil {
+		logger.Errorf("failed to get node for rollback: %v", err)
+		return
+	}
+
+	logger = c.nodeLogger(node).WithFields(log.Fields{
+		logging.FieldAction: "rollback",
+		logging.FieldState:  StateFailed,
+	})
+	operations := statePatch(node, StateFailed, time.Now(), map[string]string{
+		AnnotationFailure: cause.Error(),
+	})
+
+	if !keepCordoned && c.FailurePolicy != config.FailurePolicyKeepCordoned && HasRecyclingTaint(node) {
+		taints := make([]corev1.Taint, 0)
+		for _, taint := range node.Spec.Taints {
+			if taint.Key != TaintKeyRecycling {
+				taints = append(taints, taint)
+			}
+		}
+		operations = append(operations, taintsPatch(node, taints)...)
+		logger.Info("uncordon node")
+	}
+
+	_, err = c.patchNode(nodeName, operations)
+	if err != nil {
+		logger.Errorf("failed to roll back node: %v", err)
+	}
+}
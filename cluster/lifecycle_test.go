@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"preemptible-lifecycle-scheduler/config"
+	"testing"
+	"time"
+)
+
+func TestClient_UnScheduleNode(t *testing.T) {
+	tests := map[string]struct {
+		Node           *corev1.Node
+		ExpectedTaints int
+	}{
+		"no taints, no annotations": {
+			Node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+			},
+			ExpectedTaints: 1,
+		},
+		"existing taints": {
+			Node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "node-a",
+					Labels:      map[string]string{"cloud.google.com/gke-nodepool": "pool-a"},
+					Annotations: map[string]string{"foo": "bar"},
+				},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			ExpectedTaints: 2,
+		},
+		"already tainted": {
+			Node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{{Key: TaintKeyRecycling, Value: "true", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+			ExpectedTaints: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &Client{KubeClient: fake.NewSimpleClientset(tc.Node)}
+
+			err := client.UnScheduleNode(tc.Node)
+			if err != nil {
+				t.Fatalf("failed to unschedule node: %v", err)
+			}
+
+			node, err := client.KubeClient.CoreV1().Nodes().Get(tc.Node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get node: %v", err)
+			}
+
+			if !HasRecyclingTaint(node) || len(node.Spec.Taints) != tc.ExpectedTaints {
+				t.Errorf("expected %d taints with recycling taint, got %v", tc.ExpectedTaints, node.Spec.Taints)
+			}
+
+			if node.Spec.Unschedulable {
+				t.Errorf("expected node to be cordoned by taint only")
+			}
+
+			if GetNodeState(node) != StateDraining {
+				t.Errorf("expected state %s, got %s", StateDraining, GetNodeState(node))
+			}
+
+			if _, ok := node.Annotations[GetStateAnnotation(StateDraining)]; !ok {
+				t.Errorf("expected %s annotation, got %v", GetStateAnnotation(StateDraining), node.Annotations)
+			}
+
+			err = client.MarkNodeDeleting(node.Name)
+			if err != nil {
+				t.Fatalf("failed to mark node deleting: %v", err)
+			}
+
+			node, _ = client.KubeClient.CoreV1().Nodes().Get(tc.Node.Name, metav1.GetOptions{})
+			if GetNodeState(node) != StateDeleting {
+				t.Errorf("expected state %s, got %s", StateDeleting, GetNodeState(node))
+			}
+
+			if _, ok := node.Annotations[GetStateAnnotation(StateDraining)]; !ok {
+				t.Errorf("expected %s annotation to be kept, got %v", GetStateAnnotation(StateDraining), node.Annotations)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNode_Timeout(t *testing.T) {
+	tests := map[string]struct {
+		FailurePolicy string
+		ExpectedTaint bool
+	}{
+		"uncordon": {
+			FailurePolicy: config.FailurePolicyUncordon,
+			ExpectedTaint: false,
+		},
+		"keep cordoned": {
+			FailurePolicy: config.FailurePolicyKeepCordoned,
+			ExpectedTaint: true,
+		},
+	}
+
+	EvictionRetryInterval = 10 * time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default"},
+					Spec:       corev1.PodSpec{NodeName: "node-a"},
+				},
+			)
+			// pod never terminates
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, nil
+			})
+
+			client := &Client{
+				KubeClient:    kubeClient,
+				DeleteTimeout: 100 * time.Millisecond,
+				FailurePolicy: tc.FailurePolicy,
+			}
+
+			result, err := client.ProcessNode(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}, "test")
+			if result.Outcome != OutcomeTimedOut || !result.Cordoned || result.PodsRemaining != 1 {
+				t.Errorf("expected timed out result with 1 pod remaining, got %+v", result)
+			}
+
+			processErr, ok := err.(*ProcessError)
+			if !ok {
+				t.Fatalf("expected process error, got %v", err)
+			}
+
+			if !errors.Is(err, ErrProcessTimeout) || processErr.Step != StepDrain {
+				t.Errorf("expected timeout at %s step, got %v", StepDrain, err)
+			}
+
+			node, _ := kubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+			if GetNodeState(node) != StateFailed {
+				t.Errorf("expected state %s, got %s", StateFailed, GetNodeState(node))
+			}
+
+			if node.Annotations[AnnotationFailure] != err.Error() {
+				t.Errorf("expected failure annotation %q, got %q", err.Error(), node.Annotations[AnnotationFailure])
+			}
+
+			if HasRecyclingTaint(node) != tc.ExpectedTaint {
+				t.Errorf("expected recycling taint %v, got %v", tc.ExpectedTaint, node.Spec.Taints)
+			}
+		})
+	}
+}
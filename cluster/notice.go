@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+)
+
+// AnnotationEvictionTime is set on application pods of a node planned for recycling with the time the node is
+// going to be drained, so workloads can read it through the downward api and wind down ahead of the eviction.
+const AnnotationEvictionTime = lifecyclePrefix + "eviction-time"
+
+// SetEvictionTime annotate application pods on the node with the planned eviction time, a zero time clears
+// the annotation. Pods already carrying the same value are left untouched.
+func (c *Client) SetEvictionTime(nodeName string, evictAt time.Time) error {
+	pods, err := c.GetPods(nodeName)
+	if err != nil {
+		return err
+	}
+
+	value := ""
+	if !evictAt.IsZero() {
+		value = evictAt.UTC().Format(time.RFC3339)
+	}
+
+	for _, pod := range pods {
+		if pod.Annotations[AnnotationEvictionTime] == value {
+			continue
+		}
+
+		var operations []patchOperation
+		if value == "" {
+			operations = []patchOperation{{Op: "remove", Path: "/metadata/annotations/" + escapeJSONPointer(AnnotationEvictionTime)}}
+		} else {
+			operations = mapPatch("/metadata/annotations", pod.Annotations, map[string]string{AnnotationEvictionTime: value})
+		}
+
+		err = c.patchPod(&pod, operations)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetEvictionTime return the eviction time announced on application pods of the node, zero when none is.
+// The latest one is returned when pods disagree.
+func (c *Client) GetEvictionTime(nodeName string) (time.Time, error) {
+	pods, err := c.GetPods(nodeName)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var evictAt time.Time
+	for _, pod := range pods {
+		t, err := time.Parse(time.RFC3339, pod.Annotations[AnnotationEvictionTime])
+		if err == nil && t.After(evictAt) {
+			evictAt = t
+		}
+	}
+
+	return evictAt, nil
+}
+
+func (c *Client) patchPod(pod *corev1.Pod, operations []patchOperation) error {
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.KubeClient.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.JSONPatchType, data)
+	return err
+}
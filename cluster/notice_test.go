@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_SetEvictionTime(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(newTestPod("pod-a", "ReplicaSet"), newTestPod("ds", "DaemonSet"))
+	client := &Client{KubeClient: kubeClient}
+
+	evictAt := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		err := client.SetEvictionTime("node-a", evictAt)
+		if err != nil {
+			t.Fatalf("failed to set eviction time: %v", err)
+		}
+	}
+
+	pod, _ := kubeClient.CoreV1().Pods("default").Get("pod-a", metav1.GetOptions{})
+	if pod.Annotations[AnnotationEvictionTime] != "2020-01-01T10:00:00Z" {
+		t.Errorf("expected %v, got %v", "2020-01-01T10:00:00Z", pod.Annotations)
+	}
+
+	ds, _ := kubeClient.CoreV1().Pods("default").Get("ds", metav1.GetOptions{})
+	if _, ok := ds.Annotations[AnnotationEvictionTime]; ok {
+		t.Errorf("expected daemonset pod left alone, got %v", ds.Annotations)
+	}
+
+	// the fake clientset merges maps when applying a patch, so look at the patch sent instead of the pod
+	kubeClient.ClearActions()
+	err := client.SetEvictionTime("node-a", time.Time{})
+	if err != nil {
+		t.Fatalf("failed to clear eviction time: %v", err)
+	}
+
+	patches := make([]string, 0)
+	for _, action := range kubeClient.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patches = append(patches, string(patch.GetPatch()))
+		}
+	}
+
+	if len(patches) != 1 || !strings.Contains(patches[0], `"op":"remove"`) {
+		t.Errorf("expected annotation removed from pod-a only, got %v", patches)
+	}
+}
+
+func TestClient_GetEvictionTime(t *testing.T) {
+	pod := newTestPod("pod-a", "ReplicaSet")
+	pod.Annotations = map[string]string{AnnotationEvictionTime: "2020-01-01T10:00:00Z"}
+
+	tests := map[string]struct {
+		Client   *Client
+		Expected time.Time
+	}{
+		"announced": {
+			Client:   &Client{KubeClient: fake.NewSimpleClientset(pod, newTestPod("pod-b", "ReplicaSet"))},
+			Expected: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC),
+		},
+		"not announced": {
+			Client: &Client{KubeClient: fake.NewSimpleClientset(newTestPod("pod-b", "ReplicaSet"))},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			evictAt, err := tc.Client.GetEvictionTime("node-a")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !evictAt.Equal(tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, evictAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const (
+	// AnnotationRecycleAt hold the time the scheduler plans to recycle the node, absent when it is not planned
+	AnnotationRecycleAt = lifecyclePrefix + "recycle-at"
+
+	// ConditionRecyclePlanned is true with the planned time in its message while the node is planned for recycling
+	ConditionRecyclePlanned corev1.NodeConditionType = "RecyclePlanned"
+)
+
+// SetRecyclePlan publish when the node is going to be recycled and why on the node annotation and condition,
+// a zero time means the node is not planned for recycling. Nothing is written when the node already says so.
+func (c *Client) SetRecyclePlan(node *corev1.Node, plannedAt time.Time, reason string, message string) error {
+	value := ""
+	if !plannedAt.IsZero() {
+		value = plannedAt.UTC().Format(time.RFC3339)
+	}
+
+	if node.Annotations[AnnotationRecycleAt] != value {
+		var operations []patchOperation
+		if value == "" {
+			operations = []patchOperation{{Op: "remove", Path: "/metadata/annotations/" + escapeJSONPointer(AnnotationRecycleAt)}}
+		} else {
+			operations = mapPatch("/metadata/annotations", node.Annotations, map[string]string{AnnotationRecycleAt: value})
+		}
+
+		_, err := c.patchNode(node.Name, operations)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	status := corev1.ConditionTrue
+	if value == "" {
+		status = corev1.ConditionFalse
+	}
+
+	current := GetRecyclePlannedCondition(node)
+	if current != nil && current.Status == status && current.Reason == reason && current.Message == message {
+		return nil
+	}
+
+	now := metav1.Now()
+	condition := corev1.NodeCondition{
+		Type:               ConditionRecyclePlanned,
+		Status:             status,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	if current != nil && current.Status == status {
+		condition.LastTransitionTime = current.LastTransitionTime
+	}
+
+	// conditions are merged by type, other node conditions are left alone
+	data, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []corev1.NodeCondition{condition},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.KubeClient.CoreV1().Nodes().PatchStatus(node.Name, data)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func GetRecyclePlannedCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == ConditionRecyclePlanned {
+			return &node.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+)
+
+func TestClient_SetRecyclePlan(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+		Status:     corev1.NodeStatus{Conditions: newReadyCondition(corev1.ConditionTrue, time.Now())},
+	})
+	client := &Client{KubeClient: kubeClient}
+
+	plannedAt := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		node, _ := kubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+		kubeClient.ClearActions()
+
+		err := client.SetRecyclePlan(node, plannedAt, "Expiring", "recycle planned at 10:00")
+		if err != nil {
+			t.Fatalf("failed to set recycle plan: %v", err)
+		}
+
+		// the second time the node already carries the plan
+		if i == 1 && len(kubeClient.Actions()) != 0 {
+			t.Errorf("expected node left untouched, got %v", kubeClient.Actions())
+		}
+	}
+
+	node, _ := kubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+	if node.Annotations[AnnotationRecycleAt] != "2020-01-01T10:00:00Z" {
+		t.Errorf("expected %v, got %v", "2020-01-01T10:00:00Z", node.Annotations)
+	}
+
+	condition := GetRecyclePlannedCondition(node)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "Expiring" {
+		t.Errorf("expected planned condition, got %+v", node.Status.Conditions)
+	}
+
+	if len(node.Status.Conditions) != 2 {
+		t.Errorf("expected ready condition kept, got %+v", node.Status.Conditions)
+	}
+
+	err := client.SetRecyclePlan(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gone"}}, plannedAt, "Expiring", "")
+	if err != nil {
+		t.Errorf("expected vanished node to be ignored, got %v", err)
+	}
+}
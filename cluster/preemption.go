@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/provider"
+)
+
+const StatePreempted = "preempted"
+
+// InstanceStatusClient tell whether the cloud stopped the instance backing a node, identified by node spec.providerID
+type InstanceStatusClient interface {
+	InstanceStopped(ctx context.Context, providerID string) (bool, error)
+}
+
+// HandlePreemption cordon the node as preempted and evict its pods before the instance is stopped,
+// eviction stops when the context is done.
+func (c *Client) HandlePreemption(ctx context.Context, nodeName string) error {
+	node, err := c.KubeClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	logger := c.nodeLogger(node).WithField(logging.FieldState, StatePreempted)
+	logger.WithField(logging.FieldAction, StepCordon).Warn("node is being preempted")
+
+	err = c.cordonNode(node, StatePreempted)
+	if err != nil {
+		return err
+	}
+	c.nodeEvent(nodeName, corev1.EventTypeWarning, EventReasonCordoned, "Cordoned, instance is being preempted")
+
+	return c.DeletePods(ctx, nodeName, func(progress DrainProgress) {
+		logProgress(logger, progress)
+	})
+}
+
+// isNodePreempted return true when the node is not ready after the agent marked it as preempted, or the provider
+// confirms its instance was stopped. A node that is only unreachable may still run its pods, they must not be
+// force deleted.
+func (c *Client) isNodePreempted(node *corev1.Node) bool {
+	if !isNodeNotReady(node) {
+		return false
+	}
+
+	if GetNodeState(node) == StatePreempted {
+		return true
+	}
+
+	if c.InstanceStatus == nil || node.Spec.ProviderID == "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), InstanceLookupTimeout)
+	defer cancel()
+
+	stopped, err := c.InstanceStatus.InstanceStopped(ctx, node.Spec.ProviderID)
+	if err != nil {
+		if !errors.Is(err, provider.ErrComputeDisabled) {
+			c.nodeLogger(node).Errorf("failed to get instance status: %v", err)
+		}
+		return false
+	}
+
+	return stopped
+}
+
+func isNodeNotReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status != corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// CleanupPreemptedNodes force delete application pods stuck on preempted nodes, so their controllers
+// can start replacements right away. Return names of the preempted nodes.
+func (c *Client) CleanupPreemptedNodes() ([]string, error) {
+	nodes, err := c.GetPreemptibleNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	preempted := make([]string, 0)
+	deleted := 0
+	for _, node := range nodes.Items {
+		if !c.isNodePreempted(&node) {
+			continue
+		}
+		preempted = append(preempted, node.Name)
+		logger := c.nodeLogger(&node).WithFields(log.Fields{
+			logging.FieldAction: "cleanup",
+			logging.FieldState:  StatePreempted,
+		})
+
+		pods, err := c.GetPods(node.Name)
+		if err != nil {
+			logger.Errorf("failed to get pods of preempted node: %v", err)
+			continue
+		}
+
+		if len(pods) > 0 {
+			logger.Warnf("removing %d pods stuck on preempted node", len(pods))
+		}
+
+		for _, pod := range pods {
+			var gracePeriod int64
+			err = c.KubeClient.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{
+				GracePeriodSeconds: &gracePeriod,
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				logger.Errorf("failed to delete pod %s/%s: %v", pod.Namespace, pod.Name, err)
+				continue
+			}
+
+			deleted++
+		}
+	}
+
+	if deleted > 0 {
+		log.WithField(logging.FieldAction, "cleanup").Infof("%d pods removed from preempted nodes", deleted)
+	}
+
+	return preempted, nil
+}
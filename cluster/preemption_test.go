@@ -0,0 +1,142 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	"preemptible-lifecycle-scheduler/provider"
+	"testing"
+	"time"
+)
+
+func newReadyCondition(status corev1.ConditionStatus, since time.Time) []corev1.NodeCondition {
+	return []corev1.NodeCondition{{
+		Type:               corev1.NodeReady,
+		Status:             status,
+		LastTransitionTime: metav1.Time{Time: since},
+	}}
+}
+
+type mockInstanceStatusClient struct {
+	stopped map[string]bool
+	err     error
+}
+
+func (c *mockInstanceStatusClient) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	return c.stopped[providerID], c.err
+}
+
+func TestClient_IsNodePreempted(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		State          string
+		Conditions     []corev1.NodeCondition
+		InstanceStatus InstanceStatusClient
+		Expected       bool
+	}{
+		"ready": {
+			State:      StatePreempted,
+			Conditions: newReadyCondition(corev1.ConditionTrue, now.Add(-time.Hour)),
+			Expected:   false,
+		},
+		"not ready, marked preempted": {
+			State:      StatePreempted,
+			Conditions: newReadyCondition(corev1.ConditionUnknown, now.Add(-time.Minute)),
+			Expected:   true,
+		},
+		"not ready for a while": {
+			Conditions: newReadyCondition(corev1.ConditionUnknown, now.Add(-time.Hour)),
+			Expected:   false,
+		},
+		"not ready, instance stopped": {
+			Conditions:     newReadyCondition(corev1.ConditionUnknown, now.Add(-time.Minute)),
+			InstanceStatus: &mockInstanceStatusClient{stopped: map[string]bool{"gce://project/zone/instance-a": true}},
+			Expected:       true,
+		},
+		"not ready, instance running": {
+			Conditions:     newReadyCondition(corev1.ConditionUnknown, now.Add(-time.Hour)),
+			InstanceStatus: &mockInstanceStatusClient{stopped: map[string]bool{}},
+			Expected:       false,
+		},
+		"not ready, compute disabled": {
+			Conditions:     newReadyCondition(corev1.ConditionUnknown, now.Add(-time.Hour)),
+			InstanceStatus: &mockInstanceStatusClient{err: provider.ErrComputeDisabled},
+			Expected:       false,
+		},
+		"not ready, instance lookup failed": {
+			Conditions:     newReadyCondition(corev1.ConditionUnknown, now.Add(-time.Hour)),
+			InstanceStatus: &mockInstanceStatusClient{err: errors.New("api error")},
+			Expected:       false,
+		},
+		"no condition": {
+			Expected: false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{LabelState: tc.State}},
+				Spec:       corev1.NodeSpec{ProviderID: "gce://project/zone/instance-a"},
+				Status:     corev1.NodeStatus{Conditions: tc.Conditions},
+			}
+			client := &Client{InstanceStatus: tc.InstanceStatus}
+
+			result := client.isNodePreempted(node)
+			if result != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
+
+func TestClient_HandlePreemption(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}})
+	client := &Client{KubeClient: kubeClient}
+
+	err := client.HandlePreemption(context.Background(), "node-a")
+	if err != nil {
+		t.Fatalf("failed to handle preemption: %v", err)
+	}
+
+	node, _ := kubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+	if !HasRecyclingTaint(node) || GetNodeState(node) != StatePreempted {
+		t.Errorf("expected node cordoned as %s, got %v", StatePreempted, node)
+	}
+}
+
+func TestClient_CleanupPreemptedNodes(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-a",
+				Labels: map[string]string{"preemptible": "true", LabelState: StatePreempted},
+			},
+			Status: corev1.NodeStatus{Conditions: newReadyCondition(corev1.ConditionUnknown, time.Now())},
+		},
+		newTestPod("pod-a", "ReplicaSet"),
+		newTestPod("ds", "DaemonSet"),
+	)
+
+	client := &Client{
+		KubeClient:    kubeClient,
+		NodeSelectors: []labels.Selector{labels.SelectorFromSet(labels.Set{"preemptible": "true"})},
+	}
+
+	preempted, err := client.CleanupPreemptedNodes()
+	if err != nil {
+		t.Fatalf("failed to clean up: %v", err)
+	}
+
+	if len(preempted) != 1 || preempted[0] != "node-a" {
+		t.Errorf("expected %v, got %v", []string{"node-a"}, preempted)
+	}
+
+	pods, _ := kubeClient.CoreV1().Pods("").List(metav1.ListOptions{})
+	if len(pods.Items) != 1 || pods.Items[0].Name != "ds" {
+		t.Errorf("expected only daemonset pod left, got %v", pods.Items)
+	}
+}
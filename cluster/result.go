@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+type Outcome string
+
+const (
+	OutcomeDeleted      Outcome = "deleted"
+	OutcomeTimedOut     Outcome = "timed-out"
+	OutcomeBlockedByPDB Outcome = "blocked-by-pdb"
+	OutcomeNodeVanished Outcome = "node-vanished"
+	OutcomeRejected     Outcome = "rejected"
+	OutcomeVetoed       Outcome = "vetoed"
+	OutcomeHookFailed   Outcome = "hook-failed"
+	OutcomeDeferred     Outcome = "deferred"
+	OutcomeFailed       Outcome = "failed"
+)
+
+// Result describe what happened to a node processed by ProcessNode
+type Result struct {
+	Node               string
+	Outcome            Outcome
+	Step               string
+	Cordoned           bool
+	PodsEvicted        int
+	PodsRemaining      int
+	BlockedByPDB       bool
+	Deleted            bool
+	InstanceTerminated bool
+	// WorkloadsUnavailable count workloads of evicted pods still unavailable when the health gate timed out
+	WorkloadsUnavailable int
+	Duration             time.Duration
+	Err                  error
+}
+
+// IsRetryable return true when the node is still there and processing could succeed later
+func (r *Result) IsRetryable() bool {
+	return r.Outcome == OutcomeTimedOut || r.Outcome == OutcomeBlockedByPDB || r.Outcome == OutcomeFailed ||
+		r.Outcome == OutcomeVetoed || r.Outcome == OutcomeHookFailed || r.Outcome == OutcomeDeferred
+}
+
+// DrainProgress is reported by DeletePods for every event of a pod on the node
+type DrainProgress struct {
+	Pod          string
+	Event        PodEvent
+	Evicted      int
+	Remaining    int
+	BlockedByPDB bool
+}
+
+// progress is shared between ProcessNode and its worker, so a result can be built
+// even when the worker is still stuck at the time processing times out
+type progress struct {
+	mu        sync.Mutex
+	result    Result
+	workloads []Workload
+}
+
+func (p *progress) update(f func(result *Result)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f(&p.result)
+}
+
+func (p *progress) snapshot() Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.result
+}
+
+func (p *progress) setWorkloads(workloads []Workload) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.workloads = workloads
+}
+
+func (p *progress) getWorkloads() []Workload {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.workloads
+}
@@ -0,0 +1,413 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"preemptible-lifecycle-scheduler/hook"
+	"preemptible-lifecycle-scheduler/notify"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_ProcessNode_Result(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default", UID: "pod-a"},
+		Spec:       corev1.PodSpec{NodeName: "node-a"},
+	}
+
+	tests := map[string]struct {
+		Objects       []runtime.Object
+		EvictReaction func(kubeClient *fake.Clientset) k8stesting.ReactionFunc
+		Expected      Result
+		ExpectedErr   error
+	}{
+		"deleted": {
+			Objects: []runtime.Object{node, pod},
+			EvictReaction: func(kubeClient *fake.Clientset) k8stesting.ReactionFunc {
+				return func(action k8stesting.Action) (bool, runtime.Object, error) {
+					eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+					return true, nil, kubeClient.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+				}
+			},
+			Expected: Result{
+				Outcome:     OutcomeDeleted,
+				Step:        StepDelete,
+				Cordoned:    true,
+				PodsEvicted: 1,
+				Deleted:     true,
+			},
+		},
+		"blocked by pdb": {
+			Objects: []runtime.Object{node, pod},
+			EvictReaction: func(kubeClient *fake.Clientset) k8stesting.ReactionFunc {
+				return func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewTooManyRequests("cannot evict pod", 10)
+				}
+			},
+			Expected: Result{
+				Outcome:       OutcomeBlockedByPDB,
+				Step:          StepDrain,
+				Cordoned:      true,
+				PodsRemaining: 1,
+				BlockedByPDB:  true,
+			},
+			ExpectedErr: ErrBlockedByPDB,
+		},
+		"node vanished": {
+			Objects: []runtime.Object{},
+			Expected: Result{
+				Outcome: OutcomeNodeVanished,
+				Step:    StepCordon,
+			},
+		},
+	}
+
+	EvictionRetryInterval = 10 * time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(tc.Objects...)
+			if tc.EvictReaction != nil {
+				kubeClient.PrependReactor("create", "pods", tc.EvictReaction(kubeClient))
+			}
+
+			client := &Client{
+				KubeClient:    kubeClient,
+				DeleteTimeout: 100 * time.Millisecond,
+			}
+
+			result, err := client.ProcessNode(node, "test")
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			result.Node = ""
+			result.Duration = 0
+			result.Err = nil
+			if *result != tc.Expected {
+				t.Errorf("expected %+v, got %+v", tc.Expected, *result)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNode_DrainProgress(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default", UID: "pod-a"},
+		Spec:       corev1.PodSpec{NodeName: "node-a"},
+	}
+	kubeClient := fake.NewSimpleClientset(node, pod)
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		return true, nil, kubeClient.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+	})
+
+	var buf bytes.Buffer
+	out := log.StandardLogger().Out
+	log.SetOutput(&buf)
+	defer log.SetOutput(out)
+
+	client := &Client{KubeClient: kubeClient, DeleteTimeout: time.Second}
+	_, err := client.ProcessNode(node, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, event := range []PodEvent{PodEvicted, PodTerminated} {
+		expected := fmt.Sprintf("pod default/pod-a %s", event)
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q logged, got %s", expected, buf.String())
+		}
+	}
+}
+
+type mockComputeClient struct {
+	err        error
+	terminated []string
+}
+
+func (c *mockComputeClient) TerminateInstance(ctx context.Context, providerID string) error {
+	c.terminated = append(c.terminated, providerID)
+	return c.err
+}
+
+func TestClient_ProcessNode_TerminateInstance(t *testing.T) {
+	tests := map[string]struct {
+		Err      error
+		Expected Outcome
+	}{
+		"terminated before node is deleted": {
+			Expected: OutcomeDeleted,
+		},
+		"node kept while terminate fails": {
+			Err:      errors.New("quota exceeded"),
+			Expected: OutcomeTimedOut,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+				Spec:       corev1.NodeSpec{ProviderID: "gce://my-project/zone-a/node-a"},
+			}
+
+			computeClient := &mockComputeClient{err: tc.Err}
+			client := &Client{
+				KubeClient:    fake.NewSimpleClientset(node),
+				Compute:       computeClient,
+				DeleteTimeout: 100 * time.Millisecond,
+			}
+
+			result, _ := client.ProcessNode(node, "test")
+			if result.Outcome != tc.Expected {
+				t.Errorf("expected %v, got %+v", tc.Expected, result)
+			}
+
+			if len(computeClient.terminated) == 0 || computeClient.terminated[0] != node.Spec.ProviderID {
+				t.Errorf("expected %v, got %v", node.Spec.ProviderID, computeClient.terminated)
+			}
+
+			terminated := tc.Err == nil
+			if result.Deleted != terminated || result.InstanceTerminated != terminated {
+				t.Errorf("expected node deleted only once instance is terminated, got %+v", result)
+			}
+
+			_, err := client.KubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+			if (err == nil) == terminated {
+				t.Errorf("expected node to exist until instance is terminated, got %v", err)
+			}
+		})
+	}
+}
+
+type mockApprover struct {
+	rejectStep string
+	wait       time.Duration
+	steps      []string
+}
+
+func (a *mockApprover) Approve(ctx context.Context, nodeName string, step string) error {
+	a.steps = append(a.steps, step)
+	time.Sleep(a.wait)
+	if step == a.rejectStep {
+		return errors.New("rejected by operator")
+	}
+	return nil
+}
+
+func TestClient_ProcessNode_Approval(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	approver := &mockApprover{rejectStep: StepDelete}
+	client := &Client{
+		KubeClient:    fake.NewSimpleClientset(node),
+		Approver:      approver,
+		DeleteTimeout: 100 * time.Millisecond,
+	}
+
+	result, err := client.ProcessNode(node, "test")
+	if !errors.Is(err, ErrStepRejected) {
+		t.Errorf("expected error %v, got %v", ErrStepRejected, err)
+	}
+
+	if result.Outcome != OutcomeRejected || result.Step != StepDelete || result.Deleted {
+		t.Errorf("expected node rejected at %s step, got %+v", StepDelete, result)
+	}
+
+	expected := []string{StepCordon, StepDrain, StepDelete}
+	if len(approver.steps) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, approver.steps)
+	}
+
+	rolledBack, _ := client.KubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+	if GetNodeState(rolledBack) != StateFailed || HasRecyclingTaint(rolledBack) {
+		t.Errorf("expected node to be rolled back, got %+v", rolledBack)
+	}
+}
+
+func TestClient_ProcessNode_ApprovalOutsideDeadline(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+	client := &Client{
+		KubeClient:    fake.NewSimpleClientset(node),
+		Approver:      &mockApprover{wait: 60 * time.Millisecond},
+		DeleteTimeout: 100 * time.Millisecond,
+	}
+
+	result, err := client.ProcessNode(node, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Outcome != OutcomeDeleted {
+		t.Errorf("expected %v, got %v", OutcomeDeleted, result.Outcome)
+	}
+}
+
+type mockNotifier struct {
+	events []string
+}
+
+func (n *mockNotifier) Notify(event notify.Event) {
+	n.events = append(n.events, event.Type)
+}
+
+func TestClient_ProcessNode_Notify(t *testing.T) {
+	tests := map[string]struct {
+		Compute  ComputeClient
+		Approver Approver
+		Hooks    HookRunner
+		Expected []string
+	}{
+		"completed": {
+			Expected: []string{notify.EventDrainStarted, notify.EventDrainCompleted},
+		},
+		"failed": {
+			Compute:  &mockComputeClient{err: errors.New("quota exceeded")},
+			Expected: []string{notify.EventDrainStarted, notify.EventDrainFailed},
+		},
+		"rejected before drain": {
+			Approver: &mockApprover{rejectStep: StepDrain},
+			Expected: []string{},
+		},
+		"vetoed before drain": {
+			Hooks:    &mockHooks{vetoPhase: hook.PhasePreDrain},
+			Expected: []string{notify.EventDrainFailed},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+			notifier := &mockNotifier{}
+			client := &Client{
+				KubeClient:    fake.NewSimpleClientset(node),
+				Notifier:      notifier,
+				Compute:       tc.Compute,
+				Approver:      tc.Approver,
+				Hooks:         tc.Hooks,
+				DeleteTimeout: 100 * time.Millisecond,
+			}
+
+			_, _ = client.ProcessNode(node, "test")
+			if len(notifier.events) != len(tc.Expected) {
+				t.Fatalf("expected %v, got %v", tc.Expected, notifier.events)
+			}
+
+			for i := range tc.Expected {
+				if notifier.events[i] != tc.Expected[i] {
+					t.Errorf("expected %v, got %v", tc.Expected, notifier.events)
+				}
+			}
+		})
+	}
+}
+
+type mockHooks struct {
+	vetoPhase string
+	failPhase string
+	calls     []string
+}
+
+func (h *mockHooks) Run(ctx context.Context, phase string, nodeName string, pods []corev1.Pod) error {
+	h.calls = append(h.calls, fmt.Sprintf("%s %d", phase, len(pods)))
+	if phase == h.vetoPhase {
+		return fmt.Errorf("%w: not now", hook.ErrVetoed)
+	}
+	if phase == h.failPhase {
+		return errors.New("unexpected status 500 Internal Server Error")
+	}
+	return nil
+}
+
+func TestClient_ProcessNode_Hooks(t *testing.T) {
+	tests := map[string]struct {
+		VetoPhase        string
+		FailPhase        string
+		Expected         Outcome
+		ExpectedStep     string
+		ExpectedCalls    []string
+		ExpectedCordoned bool
+	}{
+		"proceeded": {
+			Expected:      OutcomeDeleted,
+			ExpectedStep:  StepDelete,
+			ExpectedCalls: []string{"pre-drain 1", "post-drain 1"},
+		},
+		"vetoed": {
+			VetoPhase:     hook.PhasePreDrain,
+			Expected:      OutcomeVetoed,
+			ExpectedStep:  StepPreDrain,
+			ExpectedCalls: []string{"pre-drain 1"},
+		},
+		"pre-drain failed": {
+			FailPhase:     hook.PhasePreDrain,
+			Expected:      OutcomeHookFailed,
+			ExpectedStep:  StepPreDrain,
+			ExpectedCalls: []string{"pre-drain 1"},
+		},
+		"post-drain vetoed": {
+			VetoPhase:        hook.PhasePostDrain,
+			Expected:         OutcomeHookFailed,
+			ExpectedStep:     StepPostDrain,
+			ExpectedCalls:    []string{"pre-drain 1", "post-drain 1"},
+			ExpectedCordoned: true,
+		},
+		"post-drain failed": {
+			FailPhase:        hook.PhasePostDrain,
+			Expected:         OutcomeHookFailed,
+			ExpectedStep:     StepPostDrain,
+			ExpectedCalls:    []string{"pre-drain 1", "post-drain 1"},
+			ExpectedCordoned: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default", UID: "pod-a"},
+				Spec:       corev1.PodSpec{NodeName: "node-a"},
+			}
+			kubeClient := fake.NewSimpleClientset(node, pod)
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				return true, nil, kubeClient.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+			})
+
+			hooks := &mockHooks{vetoPhase: tc.VetoPhase, failPhase: tc.FailPhase}
+			client := &Client{
+				KubeClient:    kubeClient,
+				Hooks:         hooks,
+				DeleteTimeout: 100 * time.Millisecond,
+			}
+
+			result, _ := client.ProcessNode(node, "test")
+			if result.Outcome != tc.Expected || result.Step != tc.ExpectedStep || result.IsRetryable() != (tc.Expected != OutcomeDeleted) {
+				t.Errorf("expected %s at %s step, got %+v", tc.Expected, tc.ExpectedStep, result)
+			}
+
+			if tc.Expected != OutcomeDeleted {
+				processed, _ := kubeClient.CoreV1().Nodes().Get("node-a", metav1.GetOptions{})
+				if HasRecyclingTaint(processed) != tc.ExpectedCordoned {
+					t.Errorf("expected cordoned %v, got %v", tc.ExpectedCordoned, HasRecyclingTaint(processed))
+				}
+			}
+
+			if fmt.Sprint(hooks.calls) != fmt.Sprint(tc.ExpectedCalls) {
+				t.Errorf("expected %v, got %v", tc.ExpectedCalls, hooks.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/labels"
+	"strings"
+)
+
+// ParseSelectors parse label selector strings, both equality-based (key=value, key!=value)
+// and set-based (key in (a,b), key notin (a,b), key, !key) expressions are supported.
+func ParseSelectors(selectorsStr []string) ([]labels.Selector, error) {
+	selectors := make([]labels.Selector, 0)
+	for _, selectorStr := range selectorsStr {
+		selector, err := labels.Parse(selectorStr)
+		if err != nil {
+			return selectors, fmt.Errorf("invalid label selector %q: %v", selectorStr, err)
+		}
+
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+// ParsePoolSelectors parse pool list, a bare pool name is matched against provider node pool label,
+// anything else is parsed as label selector.
+func ParsePoolSelectors(pools []string, poolLabel string) ([]labels.Selector, error) {
+	selectorsStr := make([]string, 0)
+	for _, pool := range pools {
+		pool = strings.TrimSpace(pool)
+		if pool == "" {
+			continue
+		}
+
+		if isPoolName(pool) {
+			pool = fmt.Sprintf("%s=%s", poolLabel, pool)
+		}
+
+		selectorsStr = append(selectorsStr, pool)
+	}
+
+	return ParseSelectors(selectorsStr)
+}
+
+func isPoolName(s string) bool {
+	return !strings.ContainsAny(s, "=!(), ")
+}
+
+// MatchAny return true if the label set matches at least one of the selectors
+func MatchAny(selectors []labels.Selector, set labels.Set) bool {
+	for _, selector := range selectors {
+		if selector.Matches(set) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsNodeIncluded check node labels against include and exclude pool selectors.
+// Empty include list means every pool is included, exclude always takes precedence.
+func IsNodeIncluded(included []labels.Selector, excluded []labels.Selector, set labels.Set) bool {
+	if len(included) > 0 && !MatchAny(included, set) {
+		return false
+	}
+
+	return !MatchAny(excluded, set)
+}
@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	"preemptible-lifecycle-scheduler/provider"
+	"sort"
+	"testing"
+)
+
+func TestIsNodeIncluded(t *testing.T) {
+	tests := map[string]struct {
+		IncludedPools []string
+		ExcludedPools []string
+		Labels        map[string]string
+		Expected      bool
+	}{
+		"no filter": {
+			Labels:   map[string]string{provider.GKELabelNodePool: "pool-a"},
+			Expected: true,
+		},
+		"included by name": {
+			IncludedPools: []string{"pool-a", "pool-b"},
+			Labels:        map[string]string{provider.GKELabelNodePool: "pool-b"},
+			Expected:      true,
+		},
+		"not included by name": {
+			IncludedPools: []string{"pool-a", "pool-b"},
+			Labels:        map[string]string{provider.GKELabelNodePool: "pool-c"},
+			Expected:      false,
+		},
+		"excluded by name": {
+			ExcludedPools: []string{"pool-a"},
+			Labels:        map[string]string{provider.GKELabelNodePool: "pool-a"},
+			Expected:      false,
+		},
+		"included by set-based selector": {
+			IncludedPools: []string{"team in (data, ml)"},
+			Labels:        map[string]string{provider.GKELabelNodePool: "pool-a", "team": "ml"},
+			Expected:      true,
+		},
+		"excluded by set-based selector": {
+			ExcludedPools: []string{"team notin (data)"},
+			Labels:        map[string]string{provider.GKELabelNodePool: "pool-a", "team": "ml"},
+			Expected:      false,
+		},
+		"exclude takes precedence": {
+			IncludedPools: []string{"pool-a"},
+			ExcludedPools: []string{"!dedicated"},
+			Labels:        map[string]string{provider.GKELabelNodePool: "pool-a"},
+			Expected:      false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			included, err := ParsePoolSelectors(tc.IncludedPools, provider.GKELabelNodePool)
+			if err != nil {
+				t.Fatalf("failed to parse included pools: %v", err)
+			}
+
+			excluded, err := ParsePoolSelectors(tc.ExcludedPools, provider.GKELabelNodePool)
+			if err != nil {
+				t.Fatalf("failed to parse excluded pools: %v", err)
+			}
+
+			result := IsNodeIncluded(included, excluded, labels.Set(tc.Labels))
+			if result != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
+
+func TestParseSelectors_Invalid(t *testing.T) {
+	_, err := ParseSelectors([]string{"team in (data"})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestClient_GetPreemptibleNodes(t *testing.T) {
+	newNode := func(name string, nodeLabels map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: nodeLabels,
+			},
+		}
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		newNode("preemptible-a", map[string]string{provider.GKELabelPreemptible: "true", provider.GKELabelNodePool: "pool-a"}),
+		newNode("spot-b", map[string]string{provider.GKELabelSpot: "true", provider.GKELabelNodePool: "pool-b"}),
+		newNode("spot-system", map[string]string{provider.GKELabelSpot: "true", provider.GKELabelNodePool: "system"}),
+		newNode("standard-a", map[string]string{provider.GKELabelNodePool: "pool-a"}),
+	)
+
+	selectors, err := ParseSelectors([]string{provider.GKELabelPreemptible + "=true", provider.GKELabelSpot + "=true"})
+	if err != nil {
+		t.Fatalf("failed to parse selectors: %v", err)
+	}
+
+	excluded, err := ParsePoolSelectors([]string{"system"}, provider.GKELabelNodePool)
+	if err != nil {
+		t.Fatalf("failed to parse excluded pools: %v", err)
+	}
+
+	client := &Client{
+		KubeClient:    kubeClient,
+		NodeSelectors: selectors,
+		ExcludedPools: excluded,
+	}
+
+	nodes, err := client.GetPreemptibleNodes()
+	if err != nil {
+		t.Fatalf("failed to get nodes: %v", err)
+	}
+
+	names := make([]string, 0)
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+
+	expected := []string{"preemptible-a", "spot-b"}
+	if len(names) != len(expected) || names[0] != expected[0] || names[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
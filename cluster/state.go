@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const (
+	// AnnotationSkip hold the time an operator asked to leave the node alone, it goes away with the node object
+	AnnotationSkip = lifecyclePrefix + "skip"
+
+	DefaultStateConfigMap = "preemptible-lifecycle-scheduler-state"
+	stateKeyPause         = "pause"
+)
+
+// PauseState is persisted in the state ConfigMap so a pause survives restarts
+type PauseState struct {
+	Paused bool      `json:"paused"`
+	Until  time.Time `json:"until,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// IsActive return true when the scheduler should not process nodes at the time
+func (p PauseState) IsActive(now time.Time) bool {
+	return p.Paused && (p.Until.IsZero() || now.Before(p.Until))
+}
+
+func IsNodeSkipped(node *corev1.Node) bool {
+	_, ok := node.Annotations[AnnotationSkip]
+	return ok
+}
+
+func (c *Client) GetNode(nodeName string) (*corev1.Node, error) {
+	return c.KubeClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+}
+
+// SkipNode annotate the node so it is not recycled for the rest of its lifetime
+func (c *Client) SkipNode(nodeName string) error {
+	node, err := c.GetNode(nodeName)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.patchNode(nodeName, mapPatch("/metadata/annotations", node.Annotations, map[string]string{
+		AnnotationSkip: time.Now().UTC().Format(time.RFC3339),
+	}))
+	return err
+}
+
+// LoadPauseState read pause state from the state ConfigMap, a missing ConfigMap means not paused
+func (c *Client) LoadPauseState() (PauseState, error) {
+	var state PauseState
+	configMap, err := c.KubeClient.CoreV1().ConfigMaps(c.StateNamespace).Get(c.stateConfigMap(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	data, ok := configMap.Data[stateKeyPause]
+	if !ok {
+		return state, nil
+	}
+
+	err = json.Unmarshal([]byte(data), &state)
+	return state, err
+}
+
+// SavePauseState write pause state to the state ConfigMap, creating it when needed
+func (c *Client) SavePauseState(state PauseState) error {
+	if state.Paused {
+		// a paused scheduler must not sit in a health gate
+		c.InterruptWaits()
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	configMaps := c.KubeClient.CoreV1().ConfigMaps(c.StateNamespace)
+	configMap, err := configMaps.Get(c.stateConfigMap(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.stateConfigMap(),
+				Namespace: c.StateNamespace,
+			},
+			Data: map[string]string{stateKeyPause: string(data)},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[stateKeyPause] = string(data)
+	_, err = configMaps.Update(configMap)
+	return err
+}
+
+func (c *Client) stateConfigMap() string {
+	if c.StateConfigMap == "" {
+		return DefaultStateConfigMap
+	}
+
+	return c.StateConfigMap
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+)
+
+func TestClient_PauseState(t *testing.T) {
+	client := &Client{
+		KubeClient:     fake.NewSimpleClientset(),
+		StateNamespace: "scheduler",
+	}
+
+	state, err := client.LoadPauseState()
+	if err != nil || state.Paused {
+		t.Fatalf("expected not paused without configmap, got %+v, %v", state, err)
+	}
+
+	until := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, expected := range []PauseState{{Paused: true, Until: until, Reason: "incident"}, {}} {
+		err = client.SavePauseState(expected)
+		if err != nil {
+			t.Fatalf("failed to save pause state: %v", err)
+		}
+
+		state, err = client.LoadPauseState()
+		if err != nil {
+			t.Fatalf("failed to load pause state: %v", err)
+		}
+
+		if state.Paused != expected.Paused || !state.Until.Equal(expected.Until) || state.Reason != expected.Reason {
+			t.Errorf("expected %+v, got %+v", expected, state)
+		}
+	}
+}
+
+func TestClient_SkipNode(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}})
+	client := &Client{KubeClient: kubeClient}
+
+	err := client.SkipNode("node-a")
+	if err != nil {
+		t.Fatalf("failed to skip node: %v", err)
+	}
+
+	node, _ := client.GetNode("node-a")
+	if !IsNodeSkipped(node) {
+		t.Errorf("expected node to be skipped, got %v", node.Annotations)
+	}
+}
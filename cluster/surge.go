@@ -0,0 +1,211 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/config"
+	"preemptible-lifecycle-scheduler/logging"
+	"time"
+)
+
+const (
+	// LabelSurge is set on balloon pods with the name of the node they make room for
+	LabelSurge = lifecyclePrefix + "surge-for"
+
+	BalloonImage = "k8s.gcr.io/pause:3.2"
+)
+
+var SurgePollInterval = 10 * time.Second
+
+// Scaler add an instance to the node group of a node, identified by node spec.providerID
+type Scaler interface {
+	ScaleUp(ctx context.Context, providerID string) error
+}
+
+// surgeNode make room for the pods of the node before it is drained, either by scaling its node group up
+// through the provider and waiting for the new node to be ready, or by creating a balloon pod as big as the
+// pods of the node and waiting for it to be scheduled, which makes the cluster autoscaler add a node if needed.
+func (c *Client) surgeNode(ctx context.Context, node *corev1.Node, logger *log.Entry) error {
+	timeout := c.surgeTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logger = logger.WithField(logging.FieldAction, StepSurge)
+	c.nodeEvent(node.Name, corev1.EventTypeNormal, EventReasonSurge, "Waiting for replacement capacity before draining")
+
+	var err error
+	switch c.Surge {
+	case config.SurgeProvider:
+		err = c.surgeProvider(ctx, node, logger)
+	case config.SurgeBalloon:
+		err = c.surgeBalloon(ctx, node, logger)
+	default:
+		return fmt.Errorf("unknown surge mode: %s", c.Surge)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: waited %s", ErrSurgeTimeout, timeout)
+	}
+	return err
+}
+
+func (c *Client) surgeProvider(ctx context.Context, node *corev1.Node, logger *log.Entry) error {
+	if c.Scaler == nil {
+		return fmt.Errorf("surge through provider is not enabled")
+	}
+
+	nodeList, err := c.KubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]struct{})
+	for _, n := range nodeList.Items {
+		existing[n.Name] = struct{}{}
+	}
+
+	logger.Info("scaling node group up")
+	err = c.Scaler.ScaleUp(ctx, node.Spec.ProviderID)
+	if err != nil {
+		return err
+	}
+
+	pool, hasPool := node.Labels[c.PoolLabel]
+	for {
+		nodeList, err = c.KubeClient.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			logger.Warnf("error listing nodes: %v", err)
+		}
+
+		if err == nil {
+			for i := range nodeList.Items {
+				n := &nodeList.Items[i]
+				if _, ok := existing[n.Name]; ok || !isNodeSchedulable(n) {
+					continue
+				}
+				if hasPool && n.Labels[c.PoolLabel] != pool {
+					continue
+				}
+
+				logger.Infof("replacement node %s is ready", n.Name)
+				return nil
+			}
+		}
+
+		if !sleep(ctx, SurgePollInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) surgeBalloon(ctx context.Context, node *corev1.Node, logger *log.Entry) error {
+	pods, err := c.GetPods(node.Name)
+	if err != nil {
+		return err
+	}
+
+	balloon := newBalloonPod(node, pods, c.PoolLabel)
+	balloon.Namespace = c.StateNamespace
+
+	balloons := c.KubeClient.CoreV1().Pods(balloon.Namespace)
+	// a balloon left by an earlier attempt is replaced, its size may be outdated
+	err = balloons.Delete(balloon.Name, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	_, err = balloons.Create(balloon)
+	if err != nil {
+		return err
+	}
+	// the balloon only holds room until the drain starts, evicted pods take it over
+	defer func() {
+		err := balloons.Delete(balloon.Name, &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.Errorf("failed to delete balloon pod: %v", err)
+		}
+	}()
+
+	requests := balloon.Spec.Containers[0].Resources.Requests
+	logger.Infof("waiting for balloon pod requesting %s cpu and %s memory to be scheduled",
+		requests.Cpu().String(), requests.Memory().String())
+	for {
+		pod, err := balloons.Get(balloon.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.Warnf("error getting balloon pod: %v", err)
+		}
+
+		if err == nil && pod.Spec.NodeName != "" {
+			logger.Infof("balloon pod scheduled on node %s", pod.Spec.NodeName)
+			return nil
+		}
+
+		if !sleep(ctx, SurgePollInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// newBalloonPod return a pause pod requesting what the pods of the node request, restricted to the pool
+// of the node and tolerating its taints, so it only fits where the evicted pods would
+func newBalloonPod(node *corev1.Node, pods []corev1.Pod, poolLabel string) *corev1.Pod {
+	var cpu, memory resource.Quantity
+	for i := range pods {
+		podCPU, podMemory := podRequests(&pods[i])
+		cpu.Add(podCPU)
+		memory.Add(podMemory)
+	}
+
+	tolerations := make([]corev1.Toleration, 0)
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == TaintKeyRecycling {
+			continue
+		}
+
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:      taint.Key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    taint.Value,
+			Effect:   taint.Effect,
+		})
+	}
+
+	nodeSelector := make(map[string]string)
+	if pool, ok := node.Labels[poolLabel]; ok && poolLabel != "" {
+		nodeSelector[poolLabel] = pool
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "surge-" + node.Name,
+			Labels: map[string]string{LabelSurge: node.Name},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "balloon",
+				Image: BalloonImage,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    cpu,
+						corev1.ResourceMemory: memory,
+					},
+				},
+			}},
+			NodeSelector: nodeSelector,
+			Tolerations:  tolerations,
+		},
+	}
+}
+
+// surgeTimeout return how long surgeNode waits for room, DeleteTimeout when SurgeTimeout is not set
+func (c *Client) surgeTimeout() time.Duration {
+	if c.SurgeTimeout <= 0 {
+		return c.DeleteTimeout
+	}
+	return c.SurgeTimeout
+}
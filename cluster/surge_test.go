@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"preemptible-lifecycle-scheduler/config"
+	"testing"
+	"time"
+)
+
+// mockScaler add a ready node to the pool when scaled up, unless it is broken
+type mockScaler struct {
+	kubeClient *fake.Clientset
+	broken     bool
+	delay      time.Duration
+	scaled     []string
+}
+
+func (s *mockScaler) ScaleUp(ctx context.Context, providerID string) error {
+	s.scaled = append(s.scaled, providerID)
+	if s.broken {
+		return nil
+	}
+
+	time.Sleep(s.delay)
+	replacement := newCapacityNode("node-c", "4", "8Gi")
+	_, err := s.kubeClient.CoreV1().Nodes().Create(replacement)
+	return err
+}
+
+func newSurgeClient(kubeClient *fake.Clientset, surge string, capacityCheck bool) *Client {
+	// evicted pods are gone right away
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		eviction, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		if !ok {
+			return false, nil, nil
+		}
+		return true, nil, kubeClient.Tracker().Delete(action.GetResource(), eviction.Namespace, eviction.Name)
+	})
+
+	return &Client{
+		KubeClient:     kubeClient,
+		PoolLabel:      "pool",
+		CapacityCheck:  capacityCheck,
+		Surge:          surge,
+		SurgeTimeout:   50 * time.Millisecond,
+		DeleteTimeout:  200 * time.Millisecond,
+		StateNamespace: "scheduler",
+	}
+}
+
+func TestClient_ProcessNode_SurgeProvider(t *testing.T) {
+	tests := map[string]struct {
+		CapacityCheck bool
+		Broken        bool
+		Delay         time.Duration
+		Expected      Outcome
+		ExpectedScale int
+	}{
+		"surged": {
+			Expected:      OutcomeDeleted,
+			ExpectedScale: 1,
+		},
+		"fits, no surge": {
+			CapacityCheck: true,
+			Expected:      OutcomeDeleted,
+		},
+		"surge slower than delete timeout": {
+			Delay:         250 * time.Millisecond,
+			Expected:      OutcomeDeleted,
+			ExpectedScale: 1,
+		},
+		"replacement never ready": {
+			Broken:        true,
+			Expected:      OutcomeFailed,
+			ExpectedScale: 1,
+		},
+	}
+
+	SurgePollInterval = 10 * time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := newCapacityNode("node-a", "4", "8Gi")
+			node.Spec.ProviderID = "gce://my-project/zone-a/node-a"
+			kubeClient := fake.NewSimpleClientset(node, newCapacityNode("node-b", "4", "8Gi"),
+				newCapacityPod("pod-a", "node-a", "1", "1Gi"))
+
+			scaler := &mockScaler{kubeClient: kubeClient, broken: tc.Broken, delay: tc.Delay}
+			client := newSurgeClient(kubeClient, config.SurgeProvider, tc.CapacityCheck)
+			if tc.Delay > 0 {
+				client.SurgeTimeout = 2 * tc.Delay
+			}
+			client.Scaler = scaler
+
+			result, err := client.ProcessNode(node, "test")
+			if result.Outcome != tc.Expected {
+				t.Errorf("expected %v, got %+v", tc.Expected, result)
+			}
+
+			if len(scaler.scaled) != tc.ExpectedScale {
+				t.Errorf("expected %v, got %v", tc.ExpectedScale, scaler.scaled)
+			}
+
+			if tc.Broken && (!errors.Is(err, ErrSurgeTimeout) || result.Step != StepSurge) {
+				t.Errorf("expected error %v at %s step, got %v", ErrSurgeTimeout, StepSurge, err)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNode_SurgeBalloon(t *testing.T) {
+	node := newCapacityNode("node-a", "4", "8Gi")
+	node.Labels["pool"] = "spot"
+	kubeClient := fake.NewSimpleClientset(node, newCapacityPod("pod-a", "node-a", "1500m", "1Gi"))
+	client := newSurgeClient(kubeClient, config.SurgeBalloon, false)
+
+	// stand in for kube-scheduler placing the balloon once a node was added
+	balloons := make(chan *corev1.Pod, 1)
+	go func() {
+		for {
+			balloon, err := kubeClient.CoreV1().Pods("scheduler").Get("surge-node-a", metav1.GetOptions{})
+			if err == nil {
+				balloons <- balloon.DeepCopy()
+				balloon.Spec.NodeName = "node-c"
+				_, _ = kubeClient.CoreV1().Pods("scheduler").Update(balloon)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	SurgePollInterval = 10 * time.Millisecond
+	result, err := client.ProcessNode(node, "test")
+	if err != nil || result.Outcome != OutcomeDeleted {
+		t.Fatalf("expected node deleted, got %+v, %v", result, err)
+	}
+
+	balloon := <-balloons
+	cpu := balloon.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU]
+	if cpu.Cmp(resource.MustParse("1500m")) != 0 || balloon.Spec.NodeSelector["pool"] != "spot" {
+		t.Errorf("expected balloon as big as pod-a in spot pool, got %+v", balloon.Spec)
+	}
+
+	_, err = kubeClient.CoreV1().Pods("scheduler").Get("surge-node-a", metav1.GetOptions{})
+	if err == nil {
+		t.Errorf("expected balloon to be deleted before drain")
+	}
+}
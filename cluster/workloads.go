@@ -0,0 +1,205 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/metrics"
+	"preemptible-lifecycle-scheduler/notify"
+	"sort"
+	"time"
+)
+
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindReplicaSet  = "ReplicaSet"
+)
+
+var WorkloadPollInterval = 10 * time.Second
+
+// Workload is a controller owning evicted pods, its availability is checked after a node is recycled
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (w Workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// getWorkloads return the controllers owning the pods, pods of a ReplicaSet managed by a Deployment belong
+// to the Deployment. Pods without a supported controller are left out.
+func (c *Client) getWorkloads(pods []corev1.Pod) []Workload {
+	found := make(map[Workload]struct{})
+	for _, pod := range pods {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil {
+			continue
+		}
+
+		workload := Workload{Kind: owner.Kind, Namespace: pod.Namespace, Name: owner.Name}
+		switch owner.Kind {
+		case KindStatefulSet:
+		case KindReplicaSet:
+			replicaSet, err := c.KubeClient.AppsV1().ReplicaSets(pod.Namespace).Get(owner.Name, metav1.GetOptions{})
+			if err != nil {
+				continue
+			}
+
+			if deployment := metav1.GetControllerOf(replicaSet); deployment != nil && deployment.Kind == KindDeployment {
+				workload = Workload{Kind: KindDeployment, Namespace: pod.Namespace, Name: deployment.Name}
+			}
+		default:
+			continue
+		}
+
+		found[workload] = struct{}{}
+	}
+
+	workloads := make([]Workload, 0)
+	for workload := range found {
+		workloads = append(workloads, workload)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].String() < workloads[j].String()
+	})
+
+	return workloads
+}
+
+// isWorkloadAvailable return true when the workload has as many available replicas as it wants,
+// a workload that is gone does not need to be waited for
+func (c *Client) isWorkloadAvailable(workload Workload) (bool, error) {
+	apps := c.KubeClient.AppsV1()
+	switch workload.Kind {
+	case KindDeployment:
+		deployment, err := apps.Deployments(workload.Namespace).Get(workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return apierrors.IsNotFound(err), ignoreNotFound(err)
+		}
+		return hasReplicas(deployment.Spec.Replicas, deployment.Status.AvailableReplicas), nil
+
+	case KindStatefulSet:
+		statefulSet, err := apps.StatefulSets(workload.Namespace).Get(workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return apierrors.IsNotFound(err), ignoreNotFound(err)
+		}
+		return hasReplicas(statefulSet.Spec.Replicas, statefulSet.Status.ReadyReplicas), nil
+
+	case KindReplicaSet:
+		replicaSet, err := apps.ReplicaSets(workload.Namespace).Get(workload.Name, metav1.GetOptions{})
+		if err != nil {
+			return apierrors.IsNotFound(err), ignoreNotFound(err)
+		}
+		return hasReplicas(replicaSet.Spec.Replicas, replicaSet.Status.AvailableReplicas), nil
+	}
+
+	return true, nil
+}
+
+// hasReplicas compare available replicas with desired ones, which default to 1 when not set
+func hasReplicas(desired *int32, available int32) bool {
+	if desired == nil {
+		return available >= 1
+	}
+	return available >= *desired
+}
+
+// availableWorkloads return the workloads available before the drain, those already missing replicas would
+// only hold the health gate until it times out
+func (c *Client) availableWorkloads(workloads []Workload, logger *log.Entry) []Workload {
+	available := make([]Workload, 0)
+	for _, workload := range workloads {
+		ok, err := c.isWorkloadAvailable(workload)
+		if err != nil {
+			logger.WithField(logging.FieldAction, StepHealthGate).Warnf("error checking %s: %v", workload, err)
+		}
+		if !ok {
+			logger.WithField(logging.FieldAction, StepHealthGate).Infof("%s is unavailable before drain, not waiting for it", workload)
+			continue
+		}
+		available = append(available, workload)
+	}
+
+	return available
+}
+
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// waitForWorkloads block until every workload is available again, HealthGateTimeout is over or ctx is done,
+// and return the workloads still unavailable by then
+func (c *Client) waitForWorkloads(ctx context.Context, nodeName string, workloads []Workload, logger *log.Entry) []Workload {
+	logger = logger.WithField(logging.FieldAction, StepHealthGate)
+	logger.Infof("waiting for %d workloads to be available", len(workloads))
+
+	deadline := time.Now().Add(c.HealthGateTimeout)
+	for {
+		unavailable := make([]Workload, 0)
+		for _, workload := range workloads {
+			available, err := c.isWorkloadAvailable(workload)
+			if err != nil {
+				logger.Warnf("error checking %s: %v", workload, err)
+			}
+			if !available {
+				unavailable = append(unavailable, workload)
+			}
+		}
+
+		if len(unavailable) == 0 {
+			logger.Info("workloads are available")
+			return unavailable
+		}
+
+		if !time.Now().Before(deadline) {
+			metrics.HealthGateTimeouts.Inc()
+			logger.Errorf("ALERT: %d workloads still unavailable after %s: %v", len(unavailable), c.HealthGateTimeout, unavailable)
+			c.notify(notify.Event{
+				Type:    notify.EventWorkloadsUnavailable,
+				Node:    nodeName,
+				Message: fmt.Sprintf("workloads still unavailable %s after recycling: %v", c.HealthGateTimeout, unavailable),
+				Step:    StepHealthGate,
+			})
+			return unavailable
+		}
+
+		wait := time.Until(deadline)
+		if wait > WorkloadPollInterval {
+			wait = WorkloadPollInterval
+		}
+		if !sleep(ctx, wait) {
+			logger.Warnf("stopped waiting, %d workloads still unavailable: %v", len(unavailable), unavailable)
+			return unavailable
+		}
+	}
+}
+
+// InterruptWaits stop health gate waits in progress, called when the scheduler is paused or shut down
+func (c *Client) InterruptWaits() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopHealthGate != nil {
+		c.stopHealthGate()
+	}
+	c.healthGate = nil
+	c.stopHealthGate = nil
+}
+
+func (c *Client) healthGateContext() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.healthGate == nil {
+		c.healthGate, c.stopHealthGate = context.WithCancel(context.Background())
+	}
+	return c.healthGate
+}
@@ -0,0 +1,207 @@
+package cluster
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"preemptible-lifecycle-scheduler/notify"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newOwnedPod(name string, kind string, owner string) *corev1.Pod {
+	controller := true
+	pod := newCapacityPod(name, "node-a", "100m", "128Mi")
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: kind, Name: owner, Controller: &controller}}
+	return pod
+}
+
+func newTestDeployment(name string, replicas int32, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: available},
+	}
+}
+
+func newTestReplicaSet(name string, deployment string) *appsv1.ReplicaSet {
+	controller := true
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	if deployment != "" {
+		replicaSet.OwnerReferences = []metav1.OwnerReference{{Kind: KindDeployment, Name: deployment, Controller: &controller}}
+	}
+	return replicaSet
+}
+
+func TestClient_GetWorkloads(t *testing.T) {
+	client := &Client{KubeClient: fake.NewSimpleClientset(
+		newTestReplicaSet("web-5d8f", "web"),
+		newTestReplicaSet("bare", ""),
+	)}
+
+	pods := []corev1.Pod{
+		*newOwnedPod("web-5d8f-a", KindReplicaSet, "web-5d8f"),
+		*newOwnedPod("web-5d8f-b", KindReplicaSet, "web-5d8f"),
+		*newOwnedPod("bare-a", KindReplicaSet, "bare"),
+		*newOwnedPod("db-0", KindStatefulSet, "db"),
+		*newOwnedPod("job-a", "Job", "job"),
+		*newTestPod("static", ""),
+	}
+
+	expected := []Workload{
+		{Kind: KindDeployment, Namespace: "default", Name: "web"},
+		{Kind: KindReplicaSet, Namespace: "default", Name: "bare"},
+		{Kind: KindStatefulSet, Namespace: "default", Name: "db"},
+	}
+
+	workloads := client.getWorkloads(pods)
+	if !reflect.DeepEqual(workloads, expected) {
+		t.Errorf("expected %v, got %v", expected, workloads)
+	}
+}
+
+func TestClient_IsWorkloadAvailable(t *testing.T) {
+	statefulSetReplicas := int32(3)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &statefulSetReplicas},
+		Status:     appsv1.StatefulSetStatus{ReadyReplicas: 2},
+	}
+
+	tests := map[string]struct {
+		Objects  []runtime.Object
+		Workload Workload
+		Expected bool
+	}{
+		"deployment available": {
+			Objects:  []runtime.Object{newTestDeployment("web", 2, 2)},
+			Workload: Workload{Kind: KindDeployment, Namespace: "default", Name: "web"},
+			Expected: true,
+		},
+		"deployment missing replicas": {
+			Objects:  []runtime.Object{newTestDeployment("web", 2, 1)},
+			Workload: Workload{Kind: KindDeployment, Namespace: "default", Name: "web"},
+		},
+		"statefulset not ready": {
+			Objects:  []runtime.Object{statefulSet},
+			Workload: Workload{Kind: KindStatefulSet, Namespace: "default", Name: "db"},
+		},
+		"replicaset without replicas set": {
+			Objects:  []runtime.Object{newTestReplicaSet("bare", "")},
+			Workload: Workload{Kind: KindReplicaSet, Namespace: "default", Name: "bare"},
+		},
+		"deleted workload": {
+			Workload: Workload{Kind: KindDeployment, Namespace: "default", Name: "web"},
+			Expected: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &Client{KubeClient: fake.NewSimpleClientset(tc.Objects...)}
+
+			available, err := client.isWorkloadAvailable(tc.Workload)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if available != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, available)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNode_HealthGate(t *testing.T) {
+	tests := map[string]struct {
+		Before        int32
+		After         int32
+		Interrupt     bool
+		Expected      int
+		ExpectedAlert bool
+	}{
+		"workloads available": {
+			Before: 2,
+			After:  2,
+		},
+		"workloads unavailable": {
+			Before:        2,
+			After:         1,
+			Expected:      1,
+			ExpectedAlert: true,
+		},
+		"workloads unavailable before drain": {
+			Before: 1,
+			After:  1,
+		},
+		"wait interrupted": {
+			Before:    2,
+			After:     1,
+			Interrupt: true,
+			Expected:  1,
+		},
+	}
+
+	WorkloadPollInterval = 10 * time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := newCapacityNode("node-a", "4", "8Gi")
+			kubeClient := fake.NewSimpleClientset(node,
+				newTestDeployment("web", 2, tc.Before),
+				newTestReplicaSet("web-5d8f", "web"),
+				newOwnedPod("web-5d8f-a", KindReplicaSet, "web-5d8f"),
+			)
+
+			client := newSurgeClient(kubeClient, "", false)
+			client.HealthGateTimeout = 50 * time.Millisecond
+			if tc.Interrupt {
+				client.HealthGateTimeout = time.Minute
+			}
+			notifier := &mockNotifier{}
+			client.Notifier = notifier
+
+			// the deployment loses the evicted replica
+			kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if _, ok := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction); ok {
+					deployments := appsv1.SchemeGroupVersion.WithResource("deployments")
+					return false, nil, kubeClient.Tracker().Update(deployments, newTestDeployment("web", 2, tc.After), "default")
+				}
+				return false, nil, nil
+			})
+
+			if tc.Interrupt {
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+					client.InterruptWaits()
+				}()
+			}
+
+			startedAt := time.Now()
+			result, err := client.ProcessNode(node, "test")
+			if err != nil || result.Outcome != OutcomeDeleted {
+				t.Fatalf("expected node deleted, got %+v, %v", result, err)
+			}
+
+			if result.WorkloadsUnavailable != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, result.WorkloadsUnavailable)
+			}
+
+			alerted := false
+			for _, event := range notifier.events {
+				alerted = alerted || event == notify.EventWorkloadsUnavailable
+			}
+			if alerted != tc.ExpectedAlert {
+				t.Errorf("expected alert %v, got %v", tc.ExpectedAlert, notifier.events)
+			}
+
+			if time.Since(startedAt) > 10*time.Second {
+				t.Errorf("expected health gate to end early, took %s", time.Since(startedAt))
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package compute
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	asgService = "autoscaling"
+	asgVersion = "2011-01-01"
+	ec2Service = "ec2"
+	ec2Version = "2016-11-15"
+)
+
+// AWSInstance is an EC2 instance identified by node spec.providerID
+type AWSInstance struct {
+	Zone string
+	ID   string
+}
+
+// ParseAWSProviderID parse provider id in the form of aws:///<zone>/<instance id>
+func ParseAWSProviderID(providerID string) (*AWSInstance, error) {
+	if !strings.HasPrefix(providerID, "aws://") {
+		return nil, fmt.Errorf("invalid aws provider id: %q", providerID)
+	}
+
+	p := strings.Split(strings.Trim(strings.TrimPrefix(providerID, "aws://"), "/"), "/")
+	if len(p) != 2 || p[0] == "" || !strings.HasPrefix(p[1], "i-") {
+		return nil, fmt.Errorf("invalid aws provider id: %q", providerID)
+	}
+
+	return &AWSInstance{
+		Zone: p[0],
+		ID:   p[1],
+	}, nil
+}
+
+// Region return availability zone without the zone letter
+func (i *AWSInstance) Region() string {
+	return strings.TrimRight(i.Zone, "abcdefghijklmnopqrstuvwxyz")
+}
+
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expiration is when temporary credentials stop working, zero for static keys
+	Expiration time.Time
+}
+
+// ASGClient terminate instances through their auto scaling group. Recreate keeps the desired capacity,
+// so the group launches a replacement instance.
+type ASGClient struct {
+	HTTPClient  *http.Client
+	Endpoint    string
+	EC2Endpoint string
+	Credentials AWSCredentials
+	// CredentialsSource fetch Credentials again once they are about to expire, static credentials are used when nil
+	CredentialsSource AWSCredentialsSource
+	Action            string
+
+	mu sync.Mutex
+}
+
+// NewASGClient create client using static keys, IAM roles for service accounts or the instance profile, whichever
+// is found first. Action can be empty when the client is only used to look up instances.
+func NewASGClient(action string) (*ASGClient, error) {
+	if action != "" && action != ActionRecreate && action != ActionDelete {
+		return nil, fmt.Errorf("invalid instance action: %s", action)
+	}
+
+	credentials, source := defaultAWSCredentials(http.DefaultClient)
+	return &ASGClient{
+		HTTPClient:        http.DefaultClient,
+		Credentials:       credentials,
+		CredentialsSource: source,
+		Action:            action,
+	}, nil
+}
+
+// credentials return credentials to sign requests with, fetching them again when they are about to expire
+func (c *ASGClient) credentials(ctx context.Context) (AWSCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.CredentialsSource == nil {
+		return c.Credentials, nil
+	}
+
+	if c.Credentials.AccessKeyID != "" && time.Now().Add(credentialsExpiryWindow).Before(c.Credentials.Expiration) {
+		return c.Credentials, nil
+	}
+
+	credentials, err := c.CredentialsSource(ctx)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to get aws credentials: %w", err)
+	}
+
+	c.Credentials = credentials
+	return c.Credentials, nil
+}
+
+func (c *ASGClient) TerminateInstance(ctx context.Context, providerID string) error {
+	if c.Action == "" {
+		return ErrNoAction
+	}
+
+	instance, err := ParseAWSProviderID(providerID)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("Action", "TerminateInstanceInAutoScalingGroup")
+	form.Set("Version", asgVersion)
+	form.Set("InstanceId", instance.ID)
+	form.Set("ShouldDecrementDesiredCapacity", fmt.Sprintf("%t", c.Action == ActionDelete))
+
+	_, err = c.do(ctx, c.Endpoint, asgService, instance, form)
+	return err
+}
+
+// ScaleUp raise the desired capacity of the auto scaling group owning the instance by one
+func (c *ASGClient) ScaleUp(ctx context.Context, providerID string) error {
+	instance, err := ParseAWSProviderID(providerID)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("Action", "DescribeAutoScalingInstances")
+	form.Set("Version", asgVersion)
+	form.Set("InstanceIds.member.1", instance.ID)
+
+	body, err := c.do(ctx, c.Endpoint, asgService, instance, form)
+	if err != nil {
+		return err
+	}
+
+	instances := struct {
+		GroupName []string `xml:"DescribeAutoScalingInstancesResult>AutoScalingInstances>member>AutoScalingGroupName"`
+	}{}
+	err = xml.Unmarshal(body, &instances)
+	if err != nil {
+		return err
+	}
+	if len(instances.GroupName) == 0 {
+		return fmt.Errorf("instance %s is not in an auto scaling group", instance.ID)
+	}
+
+	form = url.Values{}
+	form.Set("Action", "DescribeAutoScalingGroups")
+	form.Set("Version", asgVersion)
+	form.Set("AutoScalingGroupNames.member.1", instances.GroupName[0])
+
+	body, err = c.do(ctx, c.Endpoint, asgService, instance, form)
+	if err != nil {
+		return err
+	}
+
+	groups := struct {
+		DesiredCapacity []int `xml:"DescribeAutoScalingGroupsResult>AutoScalingGroups>member>DesiredCapacity"`
+		MaxSize         []int `xml:"DescribeAutoScalingGroupsResult>AutoScalingGroups>member>MaxSize"`
+	}{}
+	err = xml.Unmarshal(body, &groups)
+	if err != nil {
+		return err
+	}
+	if len(groups.DesiredCapacity) == 0 || len(groups.MaxSize) == 0 {
+		return fmt.Errorf("auto scaling group %s not found", instances.GroupName[0])
+	}
+	if groups.DesiredCapacity[0] >= groups.MaxSize[0] {
+		return fmt.Errorf("auto scaling group %s is already at max size %d", instances.GroupName[0], groups.MaxSize[0])
+	}
+
+	form = url.Values{}
+	form.Set("Action", "SetDesiredCapacity")
+	form.Set("Version", asgVersion)
+	form.Set("AutoScalingGroupName", instances.GroupName[0])
+	form.Set("DesiredCapacity", strconv.Itoa(groups.DesiredCapacity[0]+1))
+	form.Set("HonorCooldown", "false")
+
+	_, err = c.do(ctx, c.Endpoint, asgService, instance, form)
+	return err
+}
+
+// InstanceCreatedTime return the time the instance was launched
+func (c *ASGClient) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	instance, err := ParseAWSProviderID(providerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	form := url.Values{}
+	form.Set("Action", "DescribeInstances")
+	form.Set("Version", ec2Version)
+	form.Set("InstanceId.1", instance.ID)
+
+	body, err := c.do(ctx, c.EC2Endpoint, ec2Service, instance, form)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	result := struct {
+		LaunchTime []time.Time `xml:"reservationSet>item>instancesSet>item>launchTime"`
+	}{}
+	err = xml.Unmarshal(body, &result)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(result.LaunchTime) == 0 {
+		return time.Time{}, fmt.Errorf("instance %s not found", instance.ID)
+	}
+
+	return result.LaunchTime[0], nil
+}
+
+// InstanceStopped return true when the instance is shutting down or gone, interrupted spot instances are
+// terminated or stopped depending on their interruption behavior
+func (c *ASGClient) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	instance, err := ParseAWSProviderID(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	form := url.Values{}
+	form.Set("Action", "DescribeInstances")
+	form.Set("Version", ec2Version)
+	form.Set("InstanceId.1", instance.ID)
+
+	body, err := c.do(ctx, c.EC2Endpoint, ec2Service, instance, form)
+	if err != nil {
+		return false, err
+	}
+
+	result := struct {
+		State []string `xml:"reservationSet>item>instancesSet>item>instanceState>name"`
+	}{}
+	err = xml.Unmarshal(body, &result)
+	if err != nil {
+		return false, err
+	}
+
+	// terminated instances are only described for a while
+	if len(result.State) == 0 {
+		return true, nil
+	}
+
+	switch result.State[0] {
+	case "shutting-down", "terminated", "stopping", "stopped":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// do send signed query api request, endpoint defaults to the regional endpoint of the service
+func (c *ASGClient) do(ctx context.Context, endpoint string, service string, instance *AWSInstance, form url.Values) ([]byte, error) {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, instance.Region())
+	}
+
+	credentials, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body := form.Encode()
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signV4(req, body, credentials, instance.Region(), service, time.Now())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s: %s", service, form.Get("Action"), resp.Status, string(respBody))
+	}
+
+	return respBody, nil
+}
+
+// signV4 sign request with AWS signature version 4
+func signV4(req *http.Request, body string, credentials AWSCredentials, region string, service string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	date := t.UTC().Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := make([]string, 0)
+	for key := range req.Header {
+		headers = append(headers, strings.ToLower(key))
+	}
+	sort.Strings(headers)
+
+	canonicalHeaders := ""
+	for _, key := range headers {
+		canonicalHeaders += key + ":" + strings.TrimSpace(req.Header.Get(key)) + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,167 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// get-vanilla case from aws signature version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	credentials := AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	signV4(req, "", credentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if req.Header.Get("Authorization") != expected {
+		t.Errorf("expected %v, got %v", expected, req.Header.Get("Authorization"))
+	}
+}
+
+func TestParseAWSProviderID(t *testing.T) {
+	tests := map[string]struct {
+		ProviderID     string
+		ExpectedRegion string
+		ExpectedErr    bool
+	}{
+		"valid": {
+			ProviderID:     "aws:///ap-southeast-1b/i-0123456789abcdef0",
+			ExpectedRegion: "ap-southeast-1",
+		},
+		"other provider": {
+			ProviderID:  "gce://my-project/zone-a/instance-a",
+			ExpectedErr: true,
+		},
+		"missing zone": {
+			ProviderID:  "aws:///i-0123456789abcdef0",
+			ExpectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			instance, err := ParseAWSProviderID(tc.ProviderID)
+			if (err != nil) != tc.ExpectedErr {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if err == nil && instance.Region() != tc.ExpectedRegion {
+				t.Errorf("expected %v, got %v", tc.ExpectedRegion, instance.Region())
+			}
+		})
+	}
+}
+
+func TestASGClient_TerminateInstance(t *testing.T) {
+	tests := map[string]struct {
+		Action   string
+		Expected string
+	}{
+		"recreate": {
+			Action:   ActionRecreate,
+			Expected: "false",
+		},
+		"delete": {
+			Action:   ActionDelete,
+			Expected: "true",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var form map[string][]string
+			var authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				form = r.PostForm
+				authorization = r.Header.Get("Authorization")
+			}))
+			defer server.Close()
+
+			client := &ASGClient{
+				HTTPClient:  server.Client(),
+				Endpoint:    server.URL,
+				Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+				Action:      tc.Action,
+			}
+
+			err := client.TerminateInstance(context.Background(), "aws:///ap-southeast-1b/i-0123456789abcdef0")
+			if err != nil {
+				t.Fatalf("failed to terminate instance: %v", err)
+			}
+
+			if form["InstanceId"][0] != "i-0123456789abcdef0" || form["ShouldDecrementDesiredCapacity"][0] != tc.Expected {
+				t.Errorf("expected instance terminated with decrement %v, got %v", tc.Expected, form)
+			}
+
+			if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKID/") {
+				t.Errorf("expected signed request, got %v", authorization)
+			}
+		})
+	}
+}
+
+func TestASGClient_ScaleUp(t *testing.T) {
+	tests := map[string]struct {
+		MaxSize     int
+		Expected    string
+		ExpectedErr bool
+	}{
+		"scaled up": {
+			MaxSize:  5,
+			Expected: "3",
+		},
+		"at max size": {
+			MaxSize:     2,
+			ExpectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			desired := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				switch r.PostForm.Get("Action") {
+				case "DescribeAutoScalingInstances":
+					_, _ = fmt.Fprint(w, `<DescribeAutoScalingInstancesResponse><DescribeAutoScalingInstancesResult>`+
+						`<AutoScalingInstances><member><AutoScalingGroupName>eks-spot</AutoScalingGroupName></member>`+
+						`</AutoScalingInstances></DescribeAutoScalingInstancesResult></DescribeAutoScalingInstancesResponse>`)
+				case "DescribeAutoScalingGroups":
+					_, _ = fmt.Fprintf(w, `<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult>`+
+						`<AutoScalingGroups><member><DesiredCapacity>2</DesiredCapacity><MaxSize>%d</MaxSize></member>`+
+						`</AutoScalingGroups></DescribeAutoScalingGroupsResult></DescribeAutoScalingGroupsResponse>`, tc.MaxSize)
+				case "SetDesiredCapacity":
+					if r.PostForm.Get("AutoScalingGroupName") == "eks-spot" {
+						desired = r.PostForm.Get("DesiredCapacity")
+					}
+				}
+			}))
+			defer server.Close()
+
+			client := &ASGClient{
+				HTTPClient:  server.Client(),
+				Endpoint:    server.URL,
+				Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+			}
+
+			err := client.ScaleUp(context.Background(), "aws:///ap-southeast-1b/i-0123456789abcdef0")
+			if (err != nil) != tc.ExpectedErr {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if desired != tc.Expected {
+				t.Errorf("expected %q, got %q", tc.Expected, desired)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	stsVersion = "2011-06-15"
+
+	// credentialsExpiryWindow refresh temporary credentials a bit before they expire, requests may be retried for a while
+	credentialsExpiryWindow = 5 * time.Minute
+	instanceMetadataTTL     = "21600"
+	webIdentitySessionName  = "preemptible-lifecycle-scheduler"
+)
+
+var (
+	// STSEndpoint is where web identity tokens are exchanged, the regional endpoint of AWS_REGION when empty
+	STSEndpoint = ""
+	// InstanceMetadataEndpoint serve the credentials of the instance profile
+	InstanceMetadataEndpoint = "http://169.254.169.254"
+)
+
+// AWSCredentialsSource fetch temporary credentials, they are fetched again once Expiration is near
+type AWSCredentialsSource func(ctx context.Context) (AWSCredentials, error)
+
+// defaultAWSCredentials look for credentials the way aws sdks do: static keys from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY, then a web identity token from AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE as set by
+// IAM roles for service accounts, then the instance profile of the node through the instance metadata service.
+func defaultAWSCredentials(httpClient *http.Client) (AWSCredentials, AWSCredentialsSource) {
+	credentials := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if credentials.AccessKeyID != "" && credentials.SecretAccessKey != "" {
+		return credentials, nil
+	}
+
+	roleARN, tokenFile := os.Getenv("AWS_ROLE_ARN"), os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleARN != "" && tokenFile != "" {
+		return AWSCredentials{}, webIdentityCredentials(httpClient, roleARN, tokenFile)
+	}
+
+	return AWSCredentials{}, instanceProfileCredentials(httpClient)
+}
+
+// webIdentityCredentials exchange the projected service account token for credentials of the role
+func webIdentityCredentials(httpClient *http.Client, roleARN string, tokenFile string) AWSCredentialsSource {
+	return func(ctx context.Context) (AWSCredentials, error) {
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+
+		endpoint := STSEndpoint
+		if endpoint == "" {
+			endpoint = "https://sts.amazonaws.com/"
+			if region := os.Getenv("AWS_REGION"); region != "" {
+				endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+			}
+		}
+
+		form := url.Values{}
+		form.Set("Action", "AssumeRoleWithWebIdentity")
+		form.Set("Version", stsVersion)
+		form.Set("RoleArn", roleARN)
+		form.Set("RoleSessionName", webIdentitySessionName)
+		form.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+		req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+		body, err := doCredentialsRequest(ctx, httpClient, req)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("sts AssumeRoleWithWebIdentity: %w", err)
+		}
+
+		result := struct {
+			AccessKeyID     string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>AccessKeyId"`
+			SecretAccessKey string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SecretAccessKey"`
+			SessionToken    string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SessionToken"`
+			Expiration      time.Time `xml:"AssumeRoleWithWebIdentityResult>Credentials>Expiration"`
+		}{}
+		err = xml.Unmarshal(body, &result)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+
+		return AWSCredentials{
+			AccessKeyID:     result.AccessKeyID,
+			SecretAccessKey: result.SecretAccessKey,
+			SessionToken:    result.SessionToken,
+			Expiration:      result.Expiration,
+		}, nil
+	}
+}
+
+// instanceProfileCredentials read credentials of the instance profile from the instance metadata service (IMDSv2)
+func instanceProfileCredentials(httpClient *http.Client) AWSCredentialsSource {
+	return func(ctx context.Context) (AWSCredentials, error) {
+		req, err := http.NewRequest(http.MethodPut, InstanceMetadataEndpoint+"/latest/api/token", nil)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", instanceMetadataTTL)
+
+		token, err := doCredentialsRequest(ctx, httpClient, req)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("instance metadata token: %w", err)
+		}
+
+		get := func(path string) ([]byte, error) {
+			req, err := http.NewRequest(http.MethodGet, InstanceMetadataEndpoint+"/latest/meta-data/iam/security-credentials/"+path, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("X-aws-ec2-metadata-token", string(token))
+			return doCredentialsRequest(ctx, httpClient, req)
+		}
+
+		roles, err := get("")
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("instance profile: %w", err)
+		}
+
+		role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+		if role == "" {
+			return AWSCredentials{}, fmt.Errorf("instance profile: no role attached to the instance")
+		}
+
+		body, err := get(role)
+		if err != nil {
+			return AWSCredentials{}, fmt.Errorf("instance profile %s: %w", role, err)
+		}
+
+		result := struct {
+			AccessKeyID     string    `json:"AccessKeyId"`
+			SecretAccessKey string    `json:"SecretAccessKey"`
+			Token           string    `json:"Token"`
+			Expiration      time.Time `json:"Expiration"`
+		}{}
+		err = json.Unmarshal(body, &result)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+
+		return AWSCredentials{
+			AccessKeyID:     result.AccessKeyID,
+			SecretAccessKey: result.SecretAccessKey,
+			SessionToken:    result.Token,
+			Expiration:      result.Expiration,
+		}, nil
+	}
+}
+
+func doCredentialsRequest(ctx context.Context, httpClient *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, string(body))
+	}
+
+	return body, nil
+}
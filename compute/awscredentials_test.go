@@ -0,0 +1,136 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWebIdentityCredentials(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "service-account-token" ||
+			r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/scheduler" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session</SessionToken>
+<Expiration>%s</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`,
+			expiration.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "web-identity")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	_ = ioutil.WriteFile(tokenFile, []byte("service-account-token\n"), 0600)
+
+	STSEndpoint = server.URL
+	defer func() { STSEndpoint = "" }()
+
+	source := webIdentityCredentials(server.Client(), "arn:aws:iam::123456789012:role/scheduler", tokenFile)
+	credentials, err := source(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get credentials: %v", err)
+	}
+
+	expected := AWSCredentials{AccessKeyID: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "session", Expiration: expiration}
+	if credentials != expected {
+		t.Errorf("expected %+v, got %+v", expected, credentials)
+	}
+}
+
+func TestInstanceProfileCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			_, _ = w.Write([]byte("imds-token"))
+			return
+		}
+
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			_, _ = w.Write([]byte("node-role\n"))
+		case "/latest/meta-data/iam/security-credentials/node-role":
+			_, _ = w.Write([]byte(`{"AccessKeyId": "ASIANODE", "SecretAccessKey": "secret", "Token": "session",
+				"Expiration": "2030-01-01T00:00:00Z"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	InstanceMetadataEndpoint = server.URL
+	defer func() { InstanceMetadataEndpoint = "http://169.254.169.254" }()
+
+	credentials, err := instanceProfileCredentials(server.Client())(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get credentials: %v", err)
+	}
+
+	if credentials.AccessKeyID != "ASIANODE" || credentials.SessionToken != "session" || credentials.Expiration.Year() != 2030 {
+		t.Errorf("expected instance profile credentials, got %+v", credentials)
+	}
+}
+
+func TestASGClient_Credentials(t *testing.T) {
+	tests := map[string]struct {
+		Cached   AWSCredentials
+		Expected int
+	}{
+		"first use": {
+			Expected: 1,
+		},
+		"still valid": {
+			Cached:   AWSCredentials{AccessKeyID: "cached", Expiration: time.Now().Add(time.Hour)},
+			Expected: 0,
+		},
+		"about to expire": {
+			Cached:   AWSCredentials{AccessKeyID: "cached", Expiration: time.Now().Add(time.Minute)},
+			Expected: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			fetched := 0
+			client := &ASGClient{
+				Credentials: tc.Cached,
+				CredentialsSource: func(ctx context.Context) (AWSCredentials, error) {
+					fetched++
+					return AWSCredentials{AccessKeyID: "fresh", Expiration: time.Now().Add(time.Hour)}, nil
+				},
+			}
+
+			_, err := client.credentials(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fetched != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, fetched)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/oauth2/google"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ActionRecreate = "recreate"
+	ActionDelete   = "delete"
+
+	DefaultGCEEndpoint = "https://compute.googleapis.com/compute/v1/"
+	computeScope       = "https://www.googleapis.com/auth/compute"
+	createdByKey       = "created-by"
+)
+
+var (
+	OperationPollInterval = 5 * time.Second
+
+	ErrNoAction = errors.New("instance action is not configured")
+)
+
+// GCEInstance is a compute engine instance identified by node spec.providerID
+type GCEInstance struct {
+	Project string
+	Zone    string
+	Name    string
+}
+
+// ParseGCEProviderID parse provider id in the form of gce://<project>/<zone>/<instance>
+func ParseGCEProviderID(providerID string) (*GCEInstance, error) {
+	if !strings.HasPrefix(providerID, "gce://") {
+		return nil, fmt.Errorf("invalid gce provider id: %q", providerID)
+	}
+
+	p := strings.Split(strings.TrimPrefix(providerID, "gce://"), "/")
+	if len(p) != 3 || p[0] == "" || p[1] == "" || p[2] == "" {
+		return nil, fmt.Errorf("invalid gce provider id: %q", providerID)
+	}
+
+	return &GCEInstance{
+		Project: p[0],
+		Zone:    p[1],
+		Name:    p[2],
+	}, nil
+}
+
+// GCEClient terminate instances through the managed instance group owning them, so the group
+// creates a replacement VM with a fresh lifetime.
+type GCEClient struct {
+	HTTPClient *http.Client
+	Endpoint   string
+	Action     string
+}
+
+// NewGCEClient create client using application default credentials, action can be empty
+// when the client is only used to look up instances.
+func NewGCEClient(ctx context.Context, action string) (*GCEClient, error) {
+	if action != "" && action != ActionRecreate && action != ActionDelete {
+		return nil, fmt.Errorf("invalid instance action: %s", action)
+	}
+
+	httpClient, err := google.DefaultClient(ctx, computeScope)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCEClient{
+		HTTPClient: httpClient,
+		Endpoint:   DefaultGCEEndpoint,
+		Action:     action,
+	}, nil
+}
+
+type gceInstance struct {
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+	Status            string    `json:"status"`
+	Metadata          struct {
+		Items []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"items"`
+	} `json:"metadata"`
+}
+
+type gceOperation struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  *struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// TerminateInstance recreate or delete the instance backing the node through its instance group manager,
+// and wait for the operation to finish.
+func (c *GCEClient) TerminateInstance(ctx context.Context, providerID string) error {
+	if c.Action == "" {
+		return ErrNoAction
+	}
+
+	instance, err := ParseGCEProviderID(providerID)
+	if err != nil {
+		return err
+	}
+
+	manager, err := c.getInstanceGroupManager(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	method := "recreateInstances"
+	if c.Action == ActionDelete {
+		method = "deleteInstances"
+	}
+
+	body := map[string][]string{
+		"instances": {fmt.Sprintf("zones/%s/instances/%s", instance.Zone, instance.Name)},
+	}
+	operation := &gceOperation{}
+	err = c.do(ctx, http.MethodPost, fmt.Sprintf("projects/%s/zones/%s/instanceGroupManagers/%s/%s",
+		instance.Project, instance.Zone, manager, method), body, operation)
+	if err != nil {
+		return err
+	}
+
+	return c.waitOperation(ctx, instance, operation)
+}
+
+// ScaleUp add one instance to the managed instance group owning the instance and wait for the resize to finish
+func (c *GCEClient) ScaleUp(ctx context.Context, providerID string) error {
+	instance, err := ParseGCEProviderID(providerID)
+	if err != nil {
+		return err
+	}
+
+	manager, err := c.getInstanceGroupManager(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("projects/%s/zones/%s/instanceGroupManagers/%s", instance.Project, instance.Zone, manager)
+	group := struct {
+		TargetSize int `json:"targetSize"`
+	}{}
+	err = c.do(ctx, http.MethodGet, path, nil, &group)
+	if err != nil {
+		return err
+	}
+
+	operation := &gceOperation{}
+	err = c.do(ctx, http.MethodPost, fmt.Sprintf("%s/resize?size=%d", path, group.TargetSize+1), nil, operation)
+	if err != nil {
+		return err
+	}
+
+	return c.waitOperation(ctx, instance, operation)
+}
+
+// InstanceCreatedTime return the time compute engine created the instance
+func (c *GCEClient) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	instance, err := ParseGCEProviderID(providerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	result, err := c.getInstance(ctx, instance)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return result.CreationTimestamp, nil
+}
+
+// InstanceStopped return true when compute engine stopped the instance, preempted instances are stopping
+// then terminated
+func (c *GCEClient) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	instance, err := ParseGCEProviderID(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := c.getInstance(ctx, instance)
+	if err != nil {
+		return false, err
+	}
+
+	switch result.Status {
+	case "STOPPING", "STOPPED", "TERMINATED":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (c *GCEClient) getInstance(ctx context.Context, instance *GCEInstance) (*gceInstance, error) {
+	result := &gceInstance{}
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("projects/%s/zones/%s/instances/%s",
+		instance.Project, instance.Zone, instance.Name), nil, result)
+	return result, err
+}
+
+// getInstanceGroupManager find the instance group manager name from created-by instance metadata
+func (c *GCEClient) getInstanceGroupManager(ctx context.Context, instance *GCEInstance) (string, error) {
+	result, err := c.getInstance(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+
+	for _, item := range result.Metadata.Items {
+		if item.Key != createdByKey {
+			continue
+		}
+
+		p := strings.Split(item.Value, "/")
+		if len(p) < 2 || p[len(p)-2] != "instanceGroupManagers" {
+			break
+		}
+
+		return p[len(p)-1], nil
+	}
+
+	return "", fmt.Errorf("instance %s is not managed by an instance group", instance.Name)
+}
+
+func (c *GCEClient) waitOperation(ctx context.Context, instance *GCEInstance, operation *gceOperation) error {
+	for operation.Status != "DONE" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(OperationPollInterval):
+		}
+
+		err := c.do(ctx, http.MethodGet, fmt.Sprintf("projects/%s/zones/%s/operations/%s",
+			instance.Project, instance.Zone, operation.Name), nil, operation)
+		if err != nil {
+			return err
+		}
+	}
+
+	if operation.Error != nil && len(operation.Error.Errors) > 0 {
+		return fmt.Errorf("operation %s failed: %s: %s", operation.Name,
+			operation.Error.Errors[0].Code, operation.Error.Errors[0].Message)
+	}
+
+	return nil
+}
+
+func (c *GCEClient) do(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.Endpoint, "/")+"/"+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, string(respBody))
+	}
+
+	return json.Unmarshal(respBody, result)
+}
@@ -0,0 +1,293 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gceServer is a stand-in for compute engine api, it only knows about instances, instance group managers
+// and zone operations
+type gceServer struct {
+	mu         sync.Mutex
+	createdBy  map[string]string
+	status     map[string]string
+	operations map[string]int
+	failOp     bool
+	calls      []string
+}
+
+func newGCEServer() *gceServer {
+	return &gceServer{
+		createdBy:  make(map[string]string),
+		status:     make(map[string]string),
+		operations: make(map[string]int),
+	}
+}
+
+func (s *gceServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// projects/{project}/zones/{zone}/{collection}/{name}[/{method}]
+	p := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(p) < 6 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && p[4] == "instances" && len(p) == 6:
+		createdBy, ok := s.createdBy[p[5]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		status := s.status[p[5]]
+		if status == "" {
+			status = "RUNNING"
+		}
+
+		_, _ = fmt.Fprintf(w, `{"creationTimestamp":"2020-01-01T06:00:00.000-08:00","status":%q,"metadata":{"items":[{"key":"created-by","value":%q}]}}`,
+			status, createdBy)
+
+	case r.Method == http.MethodGet && p[4] == "instanceGroupManagers" && len(p) == 6:
+		_, _ = fmt.Fprint(w, `{"targetSize":3}`)
+
+	case r.Method == http.MethodPost && p[4] == "instanceGroupManagers" && len(p) == 7 && p[6] == "resize":
+		s.calls = append(s.calls, fmt.Sprintf("%s/%s %s", p[5], p[6], r.URL.RawQuery))
+
+		name := fmt.Sprintf("operation-%d", len(s.calls))
+		s.operations[name] = 1
+		_, _ = fmt.Fprintf(w, `{"name":%q,"status":"RUNNING"}`, name)
+
+	case r.Method == http.MethodPost && p[4] == "instanceGroupManagers" && len(p) == 7:
+		body := map[string][]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.calls = append(s.calls, fmt.Sprintf("%s/%s %v", p[5], p[6], body["instances"]))
+
+		name := fmt.Sprintf("operation-%d", len(s.calls))
+		s.operations[name] = 1
+		_, _ = fmt.Fprintf(w, `{"name":%q,"status":"RUNNING"}`, name)
+
+	case r.Method == http.MethodGet && p[4] == "operations" && len(p) == 6:
+		// operation is done on the second poll
+		s.operations[p[5]]--
+		if s.operations[p[5]] >= 0 {
+			_, _ = fmt.Fprintf(w, `{"name":%q,"status":"RUNNING"}`, p[5])
+			return
+		}
+
+		if s.failOp {
+			_, _ = fmt.Fprintf(w, `{"name":%q,"status":"DONE","error":{"errors":[{"code":"RESOURCE_NOT_FOUND","message":"not found"}]}}`, p[5])
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"name":%q,"status":"DONE"}`, p[5])
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestParseGCEProviderID(t *testing.T) {
+	tests := map[string]struct {
+		ProviderID string
+		Expected   *GCEInstance
+	}{
+		"valid": {
+			ProviderID: "gce://my-project/asia-southeast1-a/gke-cluster-pool-a-1234-abcd",
+			Expected:   &GCEInstance{Project: "my-project", Zone: "asia-southeast1-a", Name: "gke-cluster-pool-a-1234-abcd"},
+		},
+		"other provider": {
+			ProviderID: "aws:///us-east-1a/i-0123456789",
+		},
+		"missing zone": {
+			ProviderID: "gce://my-project/gke-cluster-pool-a-1234-abcd",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			instance, err := ParseGCEProviderID(tc.ProviderID)
+			if tc.Expected == nil {
+				if err == nil {
+					t.Errorf("expected error, got %v", instance)
+				}
+				return
+			}
+
+			if err != nil || *instance != *tc.Expected {
+				t.Errorf("expected %v, got %v, err: %v", tc.Expected, instance, err)
+			}
+		})
+	}
+}
+
+func TestGCEClient_TerminateInstance(t *testing.T) {
+	tests := map[string]struct {
+		Action        string
+		ProviderID    string
+		FailOperation bool
+		ExpectedCall  string
+		ExpectedErr   bool
+	}{
+		"recreate": {
+			Action:       ActionRecreate,
+			ProviderID:   "gce://my-project/zone-a/instance-a",
+			ExpectedCall: "gke-pool-a-grp/recreateInstances [zones/zone-a/instances/instance-a]",
+		},
+		"delete": {
+			Action:       ActionDelete,
+			ProviderID:   "gce://my-project/zone-a/instance-a",
+			ExpectedCall: "gke-pool-a-grp/deleteInstances [zones/zone-a/instances/instance-a]",
+		},
+		"operation failed": {
+			Action:        ActionRecreate,
+			ProviderID:    "gce://my-project/zone-a/instance-a",
+			FailOperation: true,
+			ExpectedCall:  "gke-pool-a-grp/recreateInstances [zones/zone-a/instances/instance-a]",
+			ExpectedErr:   true,
+		},
+		"not managed by instance group": {
+			Action:      ActionRecreate,
+			ProviderID:  "gce://my-project/zone-a/standalone",
+			ExpectedErr: true,
+		},
+		"instance not found": {
+			Action:      ActionRecreate,
+			ProviderID:  "gce://my-project/zone-a/instance-b",
+			ExpectedErr: true,
+		},
+	}
+
+	OperationPollInterval = time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gce := newGCEServer()
+			gce.createdBy["instance-a"] = "projects/1234/zones/zone-a/instanceGroupManagers/gke-pool-a-grp"
+			gce.createdBy["standalone"] = ""
+			gce.failOp = tc.FailOperation
+			server := httptest.NewServer(gce)
+			defer server.Close()
+
+			client := &GCEClient{
+				HTTPClient: server.Client(),
+				Endpoint:   server.URL,
+				Action:     tc.Action,
+			}
+
+			err := client.TerminateInstance(context.Background(), tc.ProviderID)
+			if (err != nil) != tc.ExpectedErr {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if tc.ExpectedCall == "" {
+				if len(gce.calls) != 0 {
+					t.Errorf("expected no call, got %v", gce.calls)
+				}
+				return
+			}
+
+			if len(gce.calls) != 1 || gce.calls[0] != tc.ExpectedCall {
+				t.Errorf("expected %v, got %v", tc.ExpectedCall, gce.calls)
+			}
+		})
+	}
+}
+
+func TestGCEClient_InstanceCreatedTime(t *testing.T) {
+	gce := newGCEServer()
+	gce.createdBy["instance-a"] = "projects/1234/zones/zone-a/instanceGroupManagers/gke-pool-a-grp"
+	server := httptest.NewServer(gce)
+	defer server.Close()
+
+	client := &GCEClient{
+		HTTPClient: server.Client(),
+		Endpoint:   server.URL,
+	}
+
+	createdAt, err := client.InstanceCreatedTime(context.Background(), "gce://my-project/zone-a/instance-a")
+	if err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+
+	expected := time.Date(2020, 1, 1, 14, 0, 0, 0, time.UTC)
+	if !createdAt.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, createdAt)
+	}
+
+	err = client.TerminateInstance(context.Background(), "gce://my-project/zone-a/instance-a")
+	if err != ErrNoAction {
+		t.Errorf("expected %v, got %v", ErrNoAction, err)
+	}
+}
+
+func TestGCEClient_InstanceStopped(t *testing.T) {
+	tests := map[string]struct {
+		Status   string
+		Expected bool
+	}{
+		"running": {
+			Status:   "RUNNING",
+			Expected: false,
+		},
+		"preempted": {
+			Status:   "TERMINATED",
+			Expected: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gce := newGCEServer()
+			gce.createdBy["instance-a"] = "projects/1234/zones/zone-a/instanceGroupManagers/gke-pool-a-grp"
+			gce.status["instance-a"] = tc.Status
+			server := httptest.NewServer(gce)
+			defer server.Close()
+
+			client := &GCEClient{
+				HTTPClient: server.Client(),
+				Endpoint:   server.URL,
+			}
+
+			stopped, err := client.InstanceStopped(context.Background(), "gce://my-project/zone-a/instance-a")
+			if err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+
+			if stopped != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, stopped)
+			}
+		})
+	}
+}
+
+func TestGCEClient_ScaleUp(t *testing.T) {
+	gce := newGCEServer()
+	gce.createdBy["instance-a"] = "projects/1234/zones/zone-a/instanceGroupManagers/gke-pool-a-grp"
+	server := httptest.NewServer(gce)
+	defer server.Close()
+
+	client := &GCEClient{
+		HTTPClient: server.Client(),
+		Endpoint:   server.URL,
+	}
+
+	OperationPollInterval = time.Millisecond
+	err := client.ScaleUp(context.Background(), "gce://my-project/zone-a/instance-a")
+	if err != nil {
+		t.Fatalf("failed to scale up: %v", err)
+	}
+
+	expected := "gke-pool-a-grp/resize size=4"
+	if len(gce.calls) != 1 || gce.calls[0] != expected {
+		t.Errorf("expected %v, got %v", expected, gce.calls)
+	}
+}
@@ -0,0 +1,331 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultAzureEndpoint = "https://management.azure.com"
+	azureAPIVersion      = "2023-03-01"
+)
+
+// AzureIMDSTokenURL serve access tokens of the managed identity of the VM
+var AzureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// AzureInstance is a scale set instance identified by node spec.providerID
+type AzureInstance struct {
+	ScaleSetID string
+	InstanceID string
+}
+
+// ParseAzureProviderID parse provider id in the form of
+// azure:///subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachineScaleSets/<name>/virtualMachines/<instance id>
+func ParseAzureProviderID(providerID string) (*AzureInstance, error) {
+	if !strings.HasPrefix(providerID, "azure://") {
+		return nil, fmt.Errorf("invalid azure provider id: %q", providerID)
+	}
+
+	resourceID := "/" + strings.Trim(strings.TrimPrefix(providerID, "azure://"), "/")
+	p := strings.Split(resourceID, "/")
+	if len(p) != 11 || !strings.EqualFold(p[7], "virtualMachineScaleSets") || !strings.EqualFold(p[9], "virtualMachines") {
+		return nil, fmt.Errorf("invalid azure scale set provider id: %q", providerID)
+	}
+
+	return &AzureInstance{
+		ScaleSetID: strings.Join(p[:9], "/"),
+		InstanceID: p[10],
+	}, nil
+}
+
+// VMSSClient reimage or delete instances of a virtual machine scale set. Reimage gives the node a fresh disk
+// and boot while keeping the scale set capacity.
+type VMSSClient struct {
+	HTTPClient *http.Client
+	Endpoint   string
+	Action     string
+	Token      func(ctx context.Context) (string, error)
+
+	// the managed identity token is reused until it is about to expire
+	mu             sync.Mutex
+	token          string
+	tokenExpiresOn time.Time
+}
+
+// NewVMSSClient create client authenticated with the managed identity of the VM it runs on,
+// action can be empty when the client is only used to look up instances.
+func NewVMSSClient(action string) (*VMSSClient, error) {
+	if action != "" && action != ActionRecreate && action != ActionDelete {
+		return nil, fmt.Errorf("invalid instance action: %s", action)
+	}
+
+	c := &VMSSClient{
+		HTTPClient: http.DefaultClient,
+		Endpoint:   DefaultAzureEndpoint,
+		Action:     action,
+	}
+	c.Token = c.managedIdentityToken
+
+	return c, nil
+}
+
+func (c *VMSSClient) TerminateInstance(ctx context.Context, providerID string) error {
+	if c.Action == "" {
+		return ErrNoAction
+	}
+
+	instance, err := ParseAzureProviderID(providerID)
+	if err != nil {
+		return err
+	}
+
+	method := "reimage"
+	if c.Action == ActionDelete {
+		method = "delete"
+	}
+
+	body, err := json.Marshal(map[string][]string{"instanceIds": {instance.InstanceID}})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost,
+		fmt.Sprintf("%s%s/%s?api-version=%s", strings.TrimSuffix(c.Endpoint, "/"), instance.ScaleSetID, method, azureAPIVersion), body)
+	if err != nil {
+		return err
+	}
+
+	operationURL := resp.header.Get("Azure-AsyncOperation")
+	if operationURL == "" {
+		return nil
+	}
+
+	return c.waitOperation(ctx, operationURL)
+}
+
+// ScaleUp add one instance to the scale set of the instance
+func (c *VMSSClient) ScaleUp(ctx context.Context, providerID string) error {
+	instance, err := ParseAzureProviderID(providerID)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s%s?api-version=%s", strings.TrimSuffix(c.Endpoint, "/"), instance.ScaleSetID, azureAPIVersion)
+	resp, err := c.do(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	scaleSet := struct {
+		Sku struct {
+			Name     string `json:"name"`
+			Tier     string `json:"tier"`
+			Capacity int    `json:"capacity"`
+		} `json:"sku"`
+	}{}
+	err = json.Unmarshal(resp.body, &scaleSet)
+	if err != nil {
+		return err
+	}
+
+	scaleSet.Sku.Capacity++
+	body, err := json.Marshal(scaleSet)
+	if err != nil {
+		return err
+	}
+
+	resp, err = c.do(ctx, http.MethodPatch, u, body)
+	if err != nil {
+		return err
+	}
+
+	operationURL := resp.header.Get("Azure-AsyncOperation")
+	if operationURL == "" {
+		return nil
+	}
+
+	return c.waitOperation(ctx, operationURL)
+}
+
+// InstanceCreatedTime return the time the scale set instance was created
+func (c *VMSSClient) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	instance, err := ParseAzureProviderID(providerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s%s/virtualMachines/%s?api-version=%s",
+		strings.TrimSuffix(c.Endpoint, "/"), instance.ScaleSetID, instance.InstanceID, azureAPIVersion), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	vm := struct {
+		Properties struct {
+			TimeCreated time.Time `json:"timeCreated"`
+		} `json:"properties"`
+	}{}
+	err = json.Unmarshal(resp.body, &vm)
+	return vm.Properties.TimeCreated, err
+}
+
+// InstanceStopped return true when the scale set instance is stopped or deallocated, evicted spot instances are
+// deallocated unless their eviction policy deletes them
+func (c *VMSSClient) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	instance, err := ParseAzureProviderID(providerID)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s%s/virtualMachines/%s/instanceView?api-version=%s",
+		strings.TrimSuffix(c.Endpoint, "/"), instance.ScaleSetID, instance.InstanceID, azureAPIVersion), nil)
+	if err != nil {
+		return false, err
+	}
+
+	view := struct {
+		Statuses []struct {
+			Code string `json:"code"`
+		} `json:"statuses"`
+	}{}
+	err = json.Unmarshal(resp.body, &view)
+	if err != nil {
+		return false, err
+	}
+
+	for _, status := range view.Statuses {
+		switch status.Code {
+		case "PowerState/stopping", "PowerState/stopped", "PowerState/deallocating", "PowerState/deallocated":
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (c *VMSSClient) waitOperation(ctx context.Context, operationURL string) error {
+	for {
+		resp, err := c.do(ctx, http.MethodGet, operationURL, nil)
+		if err != nil {
+			return err
+		}
+
+		operation := struct {
+			Status string `json:"status"`
+			Error  struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		err = json.Unmarshal(resp.body, &operation)
+		if err != nil {
+			return err
+		}
+
+		switch operation.Status {
+		case "Succeeded":
+			return nil
+		case "Failed", "Canceled":
+			return fmt.Errorf("operation %s: %s: %s", operation.Status, operation.Error.Code, operation.Error.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(OperationPollInterval):
+		}
+	}
+}
+
+type azureResponse struct {
+	header http.Header
+	body   []byte
+}
+
+func (c *VMSSClient) do(ctx context.Context, method string, u string, body []byte) (*azureResponse, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, u, resp.Status, string(respBody))
+	}
+
+	return &azureResponse{header: resp.Header, body: respBody}, nil
+}
+
+// managedIdentityToken request access token for azure resource manager from instance metadata service, the
+// token is cached until it is about to expire since the service throttles
+func (c *VMSSClient) managedIdentityToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Add(credentialsExpiryWindow).Before(c.tokenExpiresOn) {
+		return c.token, nil
+	}
+
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", DefaultAzureEndpoint+"/")
+
+	req, err := http.NewRequest(http.MethodGet, AzureIMDSTokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata", "true")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get managed identity token: %s", resp.Status)
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}{}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", err
+	}
+
+	// expires_on is in unix seconds, a token without it is not cached
+	expiresOn, _ := strconv.ParseInt(token.ExpiresOn, 10, 64)
+	c.token = token.AccessToken
+	c.tokenExpiresOn = time.Unix(expiresOn, 0)
+	return c.token, nil
+}
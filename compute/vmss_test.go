@@ -0,0 +1,215 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseAzureProviderID(t *testing.T) {
+	tests := map[string]struct {
+		ProviderID  string
+		Expected    *AzureInstance
+		ExpectedErr bool
+	}{
+		"valid": {
+			ProviderID: "azure:///subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-spot-vmss/virtualMachines/3",
+			Expected: &AzureInstance{
+				ScaleSetID: "/subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachineScaleSets/aks-spot-vmss",
+				InstanceID: "3",
+			},
+		},
+		"availability set": {
+			ProviderID:  "azure:///subscriptions/sub/resourceGroups/mc_rg/providers/Microsoft.Compute/virtualMachines/aks-vm-0",
+			ExpectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			instance, err := ParseAzureProviderID(tc.ProviderID)
+			if (err != nil) != tc.ExpectedErr {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if err == nil && *instance != *tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, instance)
+			}
+		})
+	}
+}
+
+func TestVMSSClient_TerminateInstance(t *testing.T) {
+	tests := map[string]struct {
+		Action          string
+		OperationStatus string
+		ExpectedPath    string
+		ExpectedErr     bool
+	}{
+		"recreate": {
+			Action:          ActionRecreate,
+			OperationStatus: "Succeeded",
+			ExpectedPath:    "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/reimage",
+		},
+		"delete": {
+			Action:          ActionDelete,
+			OperationStatus: "Succeeded",
+			ExpectedPath:    "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/delete",
+		},
+		"operation failed": {
+			Action:          ActionRecreate,
+			OperationStatus: "Failed",
+			ExpectedPath:    "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/reimage",
+			ExpectedErr:     true,
+		},
+	}
+
+	OperationPollInterval = time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var path string
+			var instanceIDs []string
+			polled := 0
+
+			mux := http.NewServeMux()
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			mux.HandleFunc("/operations/1", func(w http.ResponseWriter, r *http.Request) {
+				polled++
+				status := "InProgress"
+				if polled > 1 {
+					status = tc.OperationStatus
+				}
+				_, _ = fmt.Fprintf(w, `{"status":%q}`, status)
+			})
+			mux.HandleFunc("/subscriptions/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				body := map[string][]string{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				path = r.URL.Path
+				instanceIDs = body["instanceIds"]
+
+				w.Header().Set("Azure-AsyncOperation", server.URL+"/operations/1")
+				w.WriteHeader(http.StatusAccepted)
+			})
+
+			client := &VMSSClient{
+				HTTPClient: server.Client(),
+				Endpoint:   server.URL,
+				Action:     tc.Action,
+				Token: func(ctx context.Context) (string, error) {
+					return "token", nil
+				},
+			}
+
+			err := client.TerminateInstance(context.Background(),
+				"azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/7")
+			if (err != nil) != tc.ExpectedErr {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if path != tc.ExpectedPath || len(instanceIDs) != 1 || instanceIDs[0] != "7" {
+				t.Errorf("expected %v for instance 7, got %v %v", tc.ExpectedPath, path, instanceIDs)
+			}
+
+			if polled != 2 {
+				t.Errorf("expected operation to be polled until done, got %d polls", polled)
+			}
+		})
+	}
+}
+
+func TestVMSSClient_ScaleUp(t *testing.T) {
+	var patched map[string]map[string]interface{}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/operations/1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"status":"Succeeded"}`)
+	})
+	mux.HandleFunc("/subscriptions/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = fmt.Fprint(w, `{"sku":{"name":"Standard_D4s_v3","tier":"Standard","capacity":2}}`)
+			return
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&patched)
+		w.Header().Set("Azure-AsyncOperation", server.URL+"/operations/1")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	client := &VMSSClient{
+		HTTPClient: server.Client(),
+		Endpoint:   server.URL,
+		Token: func(ctx context.Context) (string, error) {
+			return "token", nil
+		},
+	}
+
+	err := client.ScaleUp(context.Background(),
+		"azure:///subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachineScaleSets/vmss/virtualMachines/7")
+	if err != nil {
+		t.Fatalf("failed to scale up: %v", err)
+	}
+
+	sku := patched["sku"]
+	if sku["capacity"] != float64(3) || sku["name"] != "Standard_D4s_v3" {
+		t.Errorf("expected capacity raised to 3, got %v", sku)
+	}
+}
+
+func TestVMSSClient_ManagedIdentityToken(t *testing.T) {
+	tests := map[string]struct {
+		ExpiresIn time.Duration
+		Expected  int
+	}{
+		"still valid": {
+			ExpiresIn: time.Hour,
+			Expected:  1,
+		},
+		"about to expire": {
+			ExpiresIn: time.Minute,
+			Expected:  2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			requested := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested++
+				if r.Header.Get("Metadata") != "true" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"access_token": "token", "expires_on": "%d"}`, time.Now().Add(tc.ExpiresIn).Unix())
+			}))
+			defer server.Close()
+
+			AzureIMDSTokenURL = server.URL
+			defer func() { AzureIMDSTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token" }()
+
+			client := &VMSSClient{HTTPClient: server.Client()}
+			for i := 0; i < 2; i++ {
+				token, err := client.managedIdentityToken(context.Background())
+				if err != nil || token != "token" {
+					t.Fatalf("expected token, got %q, %v", token, err)
+				}
+			}
+
+			if requested != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, requested)
+			}
+		})
+	}
+}
@@ -1,29 +1,195 @@
 package config
 
 import (
+	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net/url"
+	"os"
 )
 
 const (
 	EnvProduction  = "production"
 	EnvDevelopment = "development"
+
+	ProviderGKE = "gke"
+	ProviderEKS = "eks"
+	ProviderAKS = "aks"
+
+	FailurePolicyUncordon     = "uncordon"
+	FailurePolicyKeepCordoned = "keep-cordoned"
+
+	InstanceActionRecreate = "recreate"
+	InstanceActionDelete   = "delete"
+
+	SurgeProvider = "provider"
+	SurgeBalloon  = "balloon"
+
+	AgeSourceNode     = "node"
+	AgeSourceLabel    = "label"
+	AgeSourceProvider = "provider"
+
+	ApprovalTimeoutPolicyApprove = "approve"
+	ApprovalTimeoutPolicyReject  = "reject"
+
+	redacted = "<redacted>"
 )
 
 type Config struct {
 	Environment    string   `yaml:"environment"`
+	Provider       string   `yaml:"provider"`
+	NodeSelectors  []string `yaml:"node-selectors"`
 	IncludedPool   string   `yaml:"included-pool"`
 	ExcludedPool   string   `yaml:"excluded-pool"`
+	IncludedPools  []string `yaml:"included-pools"`
+	ExcludedPools  []string `yaml:"excluded-pools"`
 	GracefulPeriod int      `yaml:"graceful-period"`
+	MaxLifetime    int      `yaml:"max-lifetime"`
+	EvictionNotice int      `yaml:"eviction-notice"`
+	CapacityCheck  bool     `yaml:"capacity-check"`
+	Surge          string   `yaml:"surge"`
+	SurgeTimeout   int      `yaml:"surge-timeout"`
+	NodeAgeSource  string   `yaml:"node-age-source"`
+	NodeAgeLabel   string   `yaml:"node-age-label"`
+	FailurePolicy  string   `yaml:"failure-policy"`
+	InstanceAction string   `yaml:"instance-action"`
 	PeakHourRanges []string `yaml:"peak-hour-ranges"`
+	ListenAddress  string   `yaml:"listen-address"`
+	LogFormat      string   `yaml:"log-format"`
+	LogLevel       string   `yaml:"log-level"`
+	AuditLog       string   `yaml:"audit-log"`
+	Namespace      string   `yaml:"namespace"`
+	StateConfigMap string   `yaml:"state-configmap"`
+	AdminToken     string   `yaml:"admin-token"`
 	Debug          bool     `yaml:"debug"`
+
+	ApprovalMode          bool   `yaml:"approval-mode"`
+	ApprovalTimeout       int    `yaml:"approval-timeout"`
+	ApprovalTimeoutPolicy string `yaml:"approval-timeout-policy"`
+
+	HealthGateTimeout int `yaml:"health-gate-timeout"`
+
+	ZoneLabel           string `yaml:"zone-label"`
+	MaxDisruptedPerZone int    `yaml:"max-disrupted-per-zone"`
+
+	NodeScorers []NodeScorer `yaml:"node-scorers"`
+
+	Webhooks []Webhook `yaml:"webhooks"`
+	Hooks    []Hook    `yaml:"hooks"`
+}
+
+// Hook is called before or after a node is drained, either with an http post to URL or by running Command
+type Hook struct {
+	Name          string   `yaml:"name"`
+	Phase         string   `yaml:"phase"`
+	URL           string   `yaml:"url"`
+	Command       []string `yaml:"command"`
+	Timeout       int      `yaml:"timeout"`
+	FailurePolicy string   `yaml:"failure-policy"`
+}
+
+// NodeScorer is a built-in node scorer along with how much it counts when ordering due nodes
+type NodeScorer struct {
+	Name   string  `yaml:"name"`
+	Weight float64 `yaml:"weight"`
+}
+
+// Webhook is an outgoing notification target, the body is rendered from Template with the lifecycle event
+type Webhook struct {
+	Name     string            `yaml:"name"`
+	URL      string            `yaml:"url"`
+	Events   []string          `yaml:"events"`
+	Template string            `yaml:"template"`
+	Headers  map[string]string `yaml:"headers"`
+	Retries  *int              `yaml:"retries"`
 }
 
 func NewDefaultConfig() *Config {
 	return &Config{
 		Environment:    EnvDevelopment,
+		Provider:       ProviderGKE,
+		FailurePolicy:  FailurePolicyUncordon,
+		IncludedPools:  []string{},
+		ExcludedPools:  []string{},
 		PeakHourRanges: []string{},
+		EvictionNotice: 30,
+		SurgeTimeout:   10,
+		ZoneLabel:      "topology.kubernetes.io/zone",
+		ListenAddress:  ":8080",
+		LogFormat:      "json",
+		LogLevel:       "info",
+		AuditLog:       "stdout",
+
+		ApprovalTimeout:       10,
+		ApprovalTimeoutPolicy: ApprovalTimeoutPolicyReject,
+	}
+}
+
+// Redacted return a copy of the config safe to log, the admin token and webhook headers are hidden along with
+// webhook url paths which often hold a secret
+func (config *Config) Redacted() Config {
+	result := *config
+	if result.AdminToken != "" {
+		result.AdminToken = redacted
+	}
+
+	result.Webhooks = make([]Webhook, len(config.Webhooks))
+	for i, webhook := range config.Webhooks {
+		if u, err := url.Parse(webhook.URL); err == nil {
+			if u.User != nil || u.Path != "" || u.RawQuery != "" {
+				webhook.URL = u.Scheme + "://" + u.Host + "/" + redacted
+			}
+		} else {
+			webhook.URL = redacted
+		}
+
+		headers := make(map[string]string, len(webhook.Headers))
+		for name := range webhook.Headers {
+			headers[name] = redacted
+		}
+		webhook.Headers = headers
+
+		result.Webhooks[i] = webhook
+	}
+
+	return result
+}
+
+// GetIncludedPools merge single included-pool with included-pools list
+func (config *Config) GetIncludedPools() []string {
+	return mergePools(config.IncludedPool, config.IncludedPools)
+}
+
+// GetExcludedPools merge single excluded-pool with excluded-pools list
+func (config *Config) GetExcludedPools() []string {
+	return mergePools(config.ExcludedPool, config.ExcludedPools)
+}
+
+// IsApprovalEnabled return true when every step has to be approved, debug mode implies approval mode
+func (config *Config) IsApprovalEnabled() bool {
+	return config.ApprovalMode || config.Debug
+}
+
+// GetNamespace return namespace the scheduler runs in, taken from POD_NAMESPACE when not configured
+func (config *Config) GetNamespace() string {
+	if config.Namespace != "" {
+		return config.Namespace
 	}
+
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+
+	return "default"
+}
+
+func mergePools(pool string, pools []string) []string {
+	result := make([]string, 0)
+	if pool != "" {
+		result = append(result, pool)
+	}
+
+	return append(result, pools...)
 }
 
 func (config *Config) Load(filepath string) (err error) {
@@ -32,5 +198,64 @@ func (config *Config) Load(filepath string) (err error) {
 		return
 	}
 
-	return yaml.Unmarshal(yamlFile, config)
+	err = yaml.Unmarshal(yamlFile, config)
+	if err != nil {
+		return
+	}
+
+	return config.Validate()
+}
+
+// Validate reject unknown values of settings picking a behavior, a typo would silently pick another one
+func (config *Config) Validate() error {
+	if !isOneOf(config.Provider, "", ProviderGKE, ProviderEKS, ProviderAKS) {
+		return fmt.Errorf("unknown provider: %q", config.Provider)
+	}
+
+	if !isOneOf(config.FailurePolicy, "", FailurePolicyUncordon, FailurePolicyKeepCordoned) {
+		return fmt.Errorf("unknown failure-policy: %q", config.FailurePolicy)
+	}
+
+	if !isOneOf(config.InstanceAction, "", InstanceActionRecreate, InstanceActionDelete) {
+		return fmt.Errorf("unknown instance-action: %q", config.InstanceAction)
+	}
+
+	if !isOneOf(config.NodeAgeSource, "", AgeSourceNode, AgeSourceLabel, AgeSourceProvider) {
+		return fmt.Errorf("unknown node-age-source: %q", config.NodeAgeSource)
+	}
+
+	if !isOneOf(config.Surge, "", SurgeProvider, SurgeBalloon) {
+		return fmt.Errorf("unknown surge: %q", config.Surge)
+	}
+
+	// the added instance is only given back when the recycled one is deleted, not on recreate or failure
+	if config.Surge == SurgeProvider && config.InstanceAction != InstanceActionDelete {
+		return fmt.Errorf("surge %q needs instance-action %q so the node group shrinks back", SurgeProvider, InstanceActionDelete)
+	}
+
+	if config.IsApprovalEnabled() {
+		// steps could only be approved through the admin api
+		if config.AdminToken == "" {
+			return fmt.Errorf("approval-mode and debug need an admin-token to approve steps")
+		}
+
+		if config.ApprovalTimeout <= 0 {
+			return fmt.Errorf("approval-timeout must be positive: %d", config.ApprovalTimeout)
+		}
+
+		if !isOneOf(config.ApprovalTimeoutPolicy, ApprovalTimeoutPolicyApprove, ApprovalTimeoutPolicyReject) {
+			return fmt.Errorf("unknown approval-timeout-policy: %q", config.ApprovalTimeoutPolicy)
+		}
+	}
+
+	return nil
+}
+
+func isOneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		Config      Config
+		ExpectedErr bool
+	}{
+		"defaults": {
+			Config: *NewDefaultConfig(),
+		},
+		"known values": {
+			Config: Config{Provider: ProviderEKS, FailurePolicy: FailurePolicyKeepCordoned, InstanceAction: InstanceActionDelete},
+		},
+		"unknown provider": {
+			Config:      Config{Provider: "gce"},
+			ExpectedErr: true,
+		},
+		"unknown failure policy": {
+			Config:      Config{FailurePolicy: "keep-cordon"},
+			ExpectedErr: true,
+		},
+		"unknown instance action": {
+			Config:      Config{InstanceAction: "terminate"},
+			ExpectedErr: true,
+		},
+		"node age from provider": {
+			Config: Config{NodeAgeSource: AgeSourceProvider},
+		},
+		"unknown node age source": {
+			Config:      Config{NodeAgeSource: "instance"},
+			ExpectedErr: true,
+		},
+		"unknown surge": {
+			Config:      Config{Surge: "scale-up"},
+			ExpectedErr: true,
+		},
+		"provider surge with instance deletion": {
+			Config: Config{Surge: SurgeProvider, InstanceAction: InstanceActionDelete},
+		},
+		"provider surge without instance action": {
+			Config:      Config{Surge: SurgeProvider},
+			ExpectedErr: true,
+		},
+		"provider surge with instance recreate": {
+			Config:      Config{Surge: SurgeProvider, InstanceAction: InstanceActionRecreate},
+			ExpectedErr: true,
+		},
+		"balloon surge": {
+			Config: Config{Surge: SurgeBalloon},
+		},
+		"approval mode": {
+			Config: Config{ApprovalMode: true, AdminToken: "token", ApprovalTimeout: 10, ApprovalTimeoutPolicy: ApprovalTimeoutPolicyApprove},
+		},
+		"approval mode without admin token": {
+			Config:      Config{ApprovalMode: true, ApprovalTimeout: 10, ApprovalTimeoutPolicy: ApprovalTimeoutPolicyReject},
+			ExpectedErr: true,
+		},
+		"debug without admin token": {
+			Config:      Config{Debug: true, ApprovalTimeout: 10, ApprovalTimeoutPolicy: ApprovalTimeoutPolicyReject},
+			ExpectedErr: true,
+		},
+		"unknown approval timeout policy": {
+			Config:      Config{ApprovalMode: true, AdminToken: "token", ApprovalTimeout: 10, ApprovalTimeoutPolicy: "deny"},
+			ExpectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tc.Config.Validate()
+			if (err != nil) != tc.ExpectedErr {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := &Config{
+		AdminToken: "secret-token",
+		Webhooks: []Webhook{{
+			URL:     "https://hooks.slack.com/services/T000/B000/XXXX",
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+		}},
+	}
+
+	result := cfg.Redacted()
+
+	if result.AdminToken != redacted {
+		t.Errorf("expected %v, got %v", redacted, result.AdminToken)
+	}
+
+	if result.Webhooks[0].URL != "https://hooks.slack.com/"+redacted {
+		t.Errorf("expected %v, got %v", "https://hooks.slack.com/"+redacted, result.Webhooks[0].URL)
+	}
+
+	if result.Webhooks[0].Headers["Authorization"] != redacted {
+		t.Errorf("expected %v, got %v", redacted, result.Webhooks[0].Headers["Authorization"])
+	}
+
+	if cfg.AdminToken != "secret-token" || cfg.Webhooks[0].Headers["Authorization"] != "Bearer secret" {
+		t.Errorf("expected config to be left untouched, got %+v", cfg)
+	}
+}
@@ -3,6 +3,11 @@ module preemptible-lifecycle-scheduler
 go 1.15
 
 require (
-	gopkg.in/yaml.v2 v2.2.4
+	github.com/prometheus/client_golang v1.7.1
+	github.com/sirupsen/logrus v1.7.0
+	golang.org/x/oauth2 v0.0.0-20190402181905-9f3314589c9a
+	gopkg.in/yaml.v2 v2.2.5
+	k8s.io/api v0.15.9
+	k8s.io/apimachinery v0.15.9
 	k8s.io/client-go v0.15.9
 )
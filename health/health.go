@@ -0,0 +1,131 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckTimeout bound every check, so a hung api call fails the probe instead of hanging it
+var CheckTimeout = 5 * time.Second
+
+type Check func() error
+
+// Probes serve liveness and readiness endpoints, each endpoint is healthy when all its checks pass
+type Probes struct {
+	mu        sync.Mutex
+	liveness  map[string]Check
+	readiness map[string]Check
+}
+
+func NewProbes() *Probes {
+	return &Probes{
+		liveness:  make(map[string]Check),
+		readiness: make(map[string]Check),
+	}
+}
+
+func (p *Probes) AddLivenessCheck(name string, check Check) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.liveness[name] = check
+}
+
+func (p *Probes) AddReadinessCheck(name string, check Check) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readiness[name] = check
+}
+
+// LivenessHandler serve /healthz
+func (p *Probes) LivenessHandler() http.Handler {
+	return p.handler(func() map[string]Check { return p.liveness })
+}
+
+// ReadinessHandler serve /readyz
+func (p *Probes) ReadinessHandler() http.Handler {
+	return p.handler(func() map[string]Check { return p.readiness })
+}
+
+func (p *Probes) handler(checks func() map[string]Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		names := make([]string, 0)
+		registered := make(map[string]Check)
+		for name, check := range checks() {
+			names = append(names, name)
+			registered[name] = check
+		}
+		p.mu.Unlock()
+		sort.Strings(names)
+
+		status := http.StatusOK
+		body := ""
+		for _, name := range names {
+			err := run(registered[name])
+			if err != nil {
+				status = http.StatusServiceUnavailable
+				body += fmt.Sprintf("%s: %v\n", name, err)
+				continue
+			}
+			body += fmt.Sprintf("%s: ok\n", name)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, body)
+	})
+}
+
+func run(check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(CheckTimeout):
+		return fmt.Errorf("check timed out after %s", CheckTimeout)
+	}
+}
+
+// Heartbeat is updated by a loop before every step with how long the step is expected to take,
+// it is considered stuck once the step overruns by more than Tolerance.
+type Heartbeat struct {
+	Tolerance time.Duration
+
+	mu       sync.Mutex
+	deadline time.Time
+	step     string
+}
+
+func NewHeartbeat(tolerance time.Duration) *Heartbeat {
+	return &Heartbeat{Tolerance: tolerance}
+}
+
+// Beat record that the loop is alive and starting a step expected to take d
+func (h *Heartbeat) Beat(step string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deadline = time.Now().Add(d + h.Tolerance)
+	h.step = step
+}
+
+// Check return an error when the loop has not beaten since its last deadline
+func (h *Heartbeat) Check() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.deadline.IsZero() {
+		return nil
+	}
+
+	if time.Now().After(h.deadline) {
+		return fmt.Errorf("no heartbeat since %s deadline %s", h.step, h.deadline.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbes_ReadinessHandler(t *testing.T) {
+	tests := map[string]struct {
+		Checks   map[string]Check
+		Expected int
+	}{
+		"no checks": {
+			Checks:   map[string]Check{},
+			Expected: http.StatusOK,
+		},
+		"all passing": {
+			Checks: map[string]Check{
+				"config":     func() error { return nil },
+				"kubernetes": func() error { return nil },
+			},
+			Expected: http.StatusOK,
+		},
+		"one failing": {
+			Checks: map[string]Check{
+				"config":     func() error { return nil },
+				"kubernetes": func() error { return errors.New("connection refused") },
+			},
+			Expected: http.StatusServiceUnavailable,
+		},
+		"hanging check": {
+			Checks: map[string]Check{
+				"kubernetes": func() error {
+					time.Sleep(time.Second)
+					return nil
+				},
+			},
+			Expected: http.StatusServiceUnavailable,
+		},
+	}
+
+	CheckTimeout = 100 * time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			probes := NewProbes()
+			for checkName, check := range tc.Checks {
+				probes.AddReadinessCheck(checkName, check)
+			}
+
+			recorder := httptest.NewRecorder()
+			probes.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if recorder.Code != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestHeartbeat_Check(t *testing.T) {
+	tests := map[string]struct {
+		Beat     bool
+		Expected time.Duration
+		Healthy  bool
+	}{
+		"not started": {
+			Healthy: true,
+		},
+		"within deadline": {
+			Beat:     true,
+			Expected: time.Hour,
+			Healthy:  true,
+		},
+		"overrun": {
+			Beat:     true,
+			Expected: -time.Hour,
+			Healthy:  false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			heartbeat := NewHeartbeat(time.Minute)
+			if tc.Beat {
+				heartbeat.Beat("sleep", tc.Expected)
+			}
+
+			healthy := heartbeat.Check() == nil
+			if healthy != tc.Healthy {
+				t.Errorf("expected %v, got %v", tc.Healthy, healthy)
+			}
+		})
+	}
+}
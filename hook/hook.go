@@ -0,0 +1,244 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"os"
+	"os/exec"
+	"preemptible-lifecycle-scheduler/config"
+	"preemptible-lifecycle-scheduler/logging"
+	"time"
+)
+
+const (
+	PhasePreDrain  = "pre-drain"
+	PhasePostDrain = "post-drain"
+
+	// FailurePolicyFail stop processing the node when the hook fails (default), FailurePolicyIgnore carry on
+	FailurePolicyFail   = "fail"
+	FailurePolicyIgnore = "ignore"
+
+	ActionProceed = "proceed"
+	ActionDelay   = "delay"
+	ActionVeto    = "veto"
+
+	defaultTimeout = 60 * time.Second
+)
+
+// DefaultDelay is used when a hook asks for a delay without saying how long
+var DefaultDelay = 10 * time.Second
+
+var (
+	ErrVetoed  = errors.New("vetoed by hook")
+	ErrTimeout = errors.New("hook did not proceed in time")
+)
+
+// Input is sent to hooks as json, in the request body or on the command stdin
+type Input struct {
+	Phase string   `json:"phase"`
+	Node  string   `json:"node"`
+	Pods  []PodRef `json:"pods"`
+}
+
+type PodRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Response is optionally returned by hooks as json. An empty response means proceed, a hook asking for
+// a delay is called again once DelaySeconds has passed.
+type Response struct {
+	Action       string `json:"action"`
+	DelaySeconds int    `json:"delaySeconds"`
+	Reason       string `json:"reason"`
+}
+
+// Runner call configured hooks of a phase one after another
+type Runner struct {
+	HTTPClient *http.Client
+
+	hooks []config.Hook
+}
+
+func NewRunner(hooks []config.Hook) (*Runner, error) {
+	for _, hook := range hooks {
+		if hook.Phase != PhasePreDrain && hook.Phase != PhasePostDrain {
+			return nil, fmt.Errorf("hook %s has unknown phase %q", hook.Name, hook.Phase)
+		}
+
+		if (hook.URL == "") == (len(hook.Command) == 0) {
+			return nil, fmt.Errorf("hook %s needs either url or command", hook.Name)
+		}
+
+		if hook.FailurePolicy != "" && hook.FailurePolicy != FailurePolicyFail && hook.FailurePolicy != FailurePolicyIgnore {
+			return nil, fmt.Errorf("hook %s has unknown failure policy %q", hook.Name, hook.FailurePolicy)
+		}
+	}
+
+	return &Runner{
+		HTTPClient: &http.Client{},
+		hooks:      hooks,
+	}, nil
+}
+
+// Run call every hook of the phase with the node and its pods. An error is returned when a hook vetoes,
+// or fails with fail policy. Post-drain hooks can not veto anymore, only fail.
+func (r *Runner) Run(ctx context.Context, phase string, nodeName string, pods []corev1.Pod) error {
+	input := Input{Phase: phase, Node: nodeName, Pods: make([]PodRef, 0)}
+	for _, pod := range pods {
+		input.Pods = append(input.Pods, PodRef{Namespace: pod.Namespace, Name: pod.Name})
+	}
+
+	for _, hook := range r.hooks {
+		if hook.Phase != phase {
+			continue
+		}
+
+		logger := logging.Node(nodeName, "").WithFields(log.Fields{
+			logging.FieldAction: phase + "-hook",
+			"hook":              hook.Name,
+		})
+
+		err := r.runHook(ctx, hook, input, logger)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, ErrVetoed) && phase == PhasePreDrain {
+			return err
+		}
+
+		if hook.FailurePolicy == FailurePolicyIgnore {
+			logger.Warnf("hook failed, ignored by policy: %v", err)
+			continue
+		}
+
+		return fmt.Errorf("hook %s failed: %w", hook.Name, err)
+	}
+
+	return nil
+}
+
+// runHook call the hook until it proceeds, vetoes or runs out of time
+func (r *Runner) runHook(ctx context.Context, hook config.Hook, input Input, logger *log.Entry) error {
+	timeout := defaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		response, err := r.call(ctx, hook, input)
+		if ctx.Err() != nil {
+			return ErrTimeout
+		}
+		if err != nil {
+			return err
+		}
+
+		switch response.Action {
+		case "", ActionProceed:
+			logger.Info("hook proceeded")
+			return nil
+
+		case ActionVeto:
+			logger.Warnf("hook vetoed: %s", response.Reason)
+			return fmt.Errorf("%w: %s", ErrVetoed, response.Reason)
+
+		case ActionDelay:
+			delay := time.Duration(response.DelaySeconds) * time.Second
+			if delay <= 0 {
+				delay = DefaultDelay
+			}
+			logger.Infof("hook asked to wait %s: %s", delay, response.Reason)
+			select {
+			case <-ctx.Done():
+				return ErrTimeout
+			case <-time.After(delay):
+			}
+
+		default:
+			return fmt.Errorf("unknown hook action %q", response.Action)
+		}
+	}
+}
+
+func (r *Runner) call(ctx context.Context, hook config.Hook, input Input) (Response, error) {
+	var response Response
+	body, err := json.Marshal(input)
+	if err != nil {
+		return response, err
+	}
+
+	var output []byte
+	if hook.URL != "" {
+		output, err = r.post(ctx, hook.URL, body)
+	} else {
+		output, err = run(ctx, hook.Command, input.Node, body)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	if len(bytes.TrimSpace(output)) == 0 {
+		return response, nil
+	}
+
+	err = json.Unmarshal(output, &response)
+	if err != nil {
+		return response, fmt.Errorf("invalid hook response: %v", err)
+	}
+
+	return response, nil
+}
+
+func (r *Runner) post(ctx context.Context, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	output, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return output, nil
+}
+
+// run execute the command with the input on stdin and NODE_NAME in the environment, stdout is the response
+func run(ctx context.Context, command []string, nodeName string, input []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "NODE_NAME="+nodeName)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return output, nil
+}
@@ -0,0 +1,191 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"preemptible-lifecycle-scheduler/config"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPods = []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default"}}}
+
+// hookStub answer the responses in order, the last one is repeated, and records the inputs it got
+type hookStub struct {
+	mu        sync.Mutex
+	responses []string
+	inputs    []Input
+}
+
+func newHookStub(responses ...string) (*hookStub, *httptest.Server) {
+	stub := &hookStub{responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input Input
+		_ = json.NewDecoder(r.Body).Decode(&input)
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.inputs = append(stub.inputs, input)
+
+		response := stub.responses[0]
+		if len(stub.responses) > 1 {
+			stub.responses = stub.responses[1:]
+		}
+
+		if response == "error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+
+	return stub, server
+}
+
+func TestRunner_Run_HTTP(t *testing.T) {
+	tests := map[string]struct {
+		Phase         string
+		Responses     []string
+		FailurePolicy string
+		ExpectedCalls int
+		ExpectedErr   error
+	}{
+		"empty response proceeds": {
+			Phase:         PhasePreDrain,
+			Responses:     []string{""},
+			ExpectedCalls: 1,
+		},
+		"delayed then proceeds": {
+			Phase:         PhasePreDrain,
+			Responses:     []string{`{"action": "delay"}`, `{"action": "delay"}`, `{"action": "proceed"}`},
+			ExpectedCalls: 3,
+		},
+		"vetoed": {
+			Phase:         PhasePreDrain,
+			Responses:     []string{`{"action": "veto", "reason": "batch job running"}`},
+			FailurePolicy: FailurePolicyIgnore,
+			ExpectedCalls: 1,
+			ExpectedErr:   ErrVetoed,
+		},
+		"veto after drain is a failure": {
+			Phase:         PhasePostDrain,
+			Responses:     []string{`{"action": "veto"}`},
+			FailurePolicy: FailurePolicyIgnore,
+			ExpectedCalls: 1,
+		},
+		"delayed past timeout": {
+			Phase:         PhasePreDrain,
+			Responses:     []string{`{"action": "delay"}`},
+			ExpectedCalls: -1,
+			ExpectedErr:   ErrTimeout,
+		},
+		"failed, ignored": {
+			Phase:         PhasePreDrain,
+			Responses:     []string{"error"},
+			FailurePolicy: FailurePolicyIgnore,
+			ExpectedCalls: 1,
+		},
+	}
+
+	DefaultDelay = 10 * time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			stub, server := newHookStub(tc.Responses...)
+			defer server.Close()
+
+			runner, err := NewRunner([]config.Hook{
+				{Name: "test", Phase: tc.Phase, URL: server.URL, Timeout: 1, FailurePolicy: tc.FailurePolicy},
+			})
+			if err != nil {
+				t.Fatalf("failed to create runner: %v", err)
+			}
+
+			err = runner.Run(context.Background(), tc.Phase, "node-a", testPods)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			if tc.ExpectedCalls >= 0 && len(stub.inputs) != tc.ExpectedCalls {
+				t.Errorf("expected %v, got %v", tc.ExpectedCalls, len(stub.inputs))
+			}
+
+			input := stub.inputs[0]
+			if input.Node != "node-a" || input.Phase != tc.Phase || len(input.Pods) != 1 || input.Pods[0].Name != "pod-a" {
+				t.Errorf("unexpected input %+v", input)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_Command(t *testing.T) {
+	tests := map[string]struct {
+		Command     []string
+		ExpectedErr error
+	}{
+		"proceeds": {
+			Command: []string{"sh", "-c", `grep -q '"name":"pod-a"' && test "$NODE_NAME" = node-a`},
+		},
+		"vetoed": {
+			Command:     []string{"sh", "-c", `echo '{"action": "veto", "reason": "busy"}'`},
+			ExpectedErr: ErrVetoed,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			runner, err := NewRunner([]config.Hook{{Name: "test", Phase: PhasePreDrain, Command: tc.Command}})
+			if err != nil {
+				t.Fatalf("failed to create runner: %v", err)
+			}
+
+			err = runner.Run(context.Background(), PhasePreDrain, "node-a", testPods)
+			if !errors.Is(err, tc.ExpectedErr) {
+				t.Errorf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+
+	runner, _ := NewRunner([]config.Hook{{Name: "test", Phase: PhasePreDrain, Command: []string{"false"}}})
+	err := runner.Run(context.Background(), PhasePreDrain, "node-a", testPods)
+	if err == nil {
+		t.Errorf("expected failing command to fail the hook")
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	tests := map[string]struct {
+		Hook     config.Hook
+		Expected bool
+	}{
+		"valid": {
+			Hook:     config.Hook{Name: "a", Phase: PhasePostDrain, URL: "http://hook"},
+			Expected: true,
+		},
+		"unknown phase": {
+			Hook: config.Hook{Name: "a", Phase: "before", URL: "http://hook"},
+		},
+		"url and command": {
+			Hook: config.Hook{Name: "a", Phase: PhasePreDrain, URL: "http://hook", Command: []string{"true"}},
+		},
+		"unknown failure policy": {
+			Hook: config.Hook{Name: "a", Phase: PhasePreDrain, URL: "http://hook", FailurePolicy: "retry"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRunner([]config.Hook{tc.Hook})
+			if (err == nil) != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, err)
+			}
+		})
+	}
+}
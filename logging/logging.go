@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+
+	FieldNode     = "node"
+	FieldPool     = "pool"
+	FieldState    = "state"
+	FieldDeadline = "deadline"
+	FieldAction   = "action"
+)
+
+// Configure set format and level of the standard logger, every package logs through it
+func Configure(format string, level string) error {
+	switch format {
+	case FormatJSON:
+		log.SetFormatter(&log.JSONFormatter{TimestampFormat: time.RFC3339})
+	case FormatText:
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true, TimestampFormat: time.RFC3339})
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(logLevel)
+
+	return nil
+}
+
+// Node return a log entry carrying node name and pool, pool is omitted when unknown
+func Node(name string, pool string) *log.Entry {
+	fields := log.Fields{FieldNode: name}
+	if pool != "" {
+		fields[FieldPool] = pool
+	}
+
+	return log.WithFields(fields)
+}
+
+// Deadline format deadline field consistently across records
+func Deadline(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"testing"
+)
+
+// saveLogger return a function restoring output, formatter and level of the standard logger, tests
+// configuring it must not leak into the next ones
+func saveLogger() func() {
+	logger := log.StandardLogger()
+	out, formatter, level := logger.Out, logger.Formatter, logger.GetLevel()
+
+	return func() {
+		log.SetOutput(out)
+		log.SetFormatter(formatter)
+		log.SetLevel(level)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	defer saveLogger()()
+
+	tests := map[string]struct {
+		Format  string
+		Level   string
+		IsError bool
+	}{
+		"json":           {Format: FormatJSON, Level: "info"},
+		"text":           {Format: FormatText, Level: "debug"},
+		"unknown format": {Format: "xml", Level: "info", IsError: true},
+		"unknown level":  {Format: FormatJSON, Level: "verbose", IsError: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Configure(tc.Format, tc.Level)
+			if (err != nil) != tc.IsError {
+				t.Errorf("expected error %v, got %v", tc.IsError, err)
+			}
+		})
+	}
+}
+
+func TestNode(t *testing.T) {
+	defer saveLogger()()
+
+	err := Configure(FormatJSON, "info")
+	if err != nil {
+		t.Fatalf("failed to configure: %v", err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	Node("node-a", "pool-a").WithField(FieldAction, "cordon").Info("cordoning node")
+
+	record := make(map[string]interface{})
+	err = json.Unmarshal(buf.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("failed to parse record %s: %v", buf.String(), err)
+	}
+
+	expected := map[string]string{
+		FieldNode:   "node-a",
+		FieldPool:   "pool-a",
+		FieldAction: "cordon",
+		"level":     "info",
+		"msg":       "cordoning node",
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, record[key])
+		}
+	}
+}
@@ -1,44 +1,197 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
+	"preemptible-lifecycle-scheduler/agent"
+	"preemptible-lifecycle-scheduler/api"
+	"preemptible-lifecycle-scheduler/approval"
+	"preemptible-lifecycle-scheduler/audit"
 	"preemptible-lifecycle-scheduler/cluster"
 	"preemptible-lifecycle-scheduler/config"
+	"preemptible-lifecycle-scheduler/health"
+	"preemptible-lifecycle-scheduler/hook"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/metrics"
+	"preemptible-lifecycle-scheduler/notify"
 	"preemptible-lifecycle-scheduler/peakhour"
+	"preemptible-lifecycle-scheduler/provider"
 	"preemptible-lifecycle-scheduler/scheduler"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
+const (
+	modeScheduler = "scheduler"
+	modeAgent     = "agent"
+	modeAudit     = "audit"
+
+	preemptionCleanupInterval = 1 * time.Minute
+	heartbeatTolerance        = 5 * time.Minute
+)
+
 func main() {
+	mode := flag.String("mode", modeScheduler, "run as central \"scheduler\", as node preemption \"agent\" or search \"audit\" log")
+	auditNode := flag.String("node", "", "audit mode: only show records of the node")
+	auditSince := flag.String("since", "", "audit mode: only show records made at or after RFC3339 time")
+	auditUntil := flag.String("until", "", "audit mode: only show records made at or before RFC3339 time")
+	flag.Parse()
+
+	if *mode == modeAudit {
+		err := searchAudit(flag.Args(), *auditNode, *auditSince, *auditUntil)
+		if err != nil {
+			log.Fatalf("failed to search audit log: %v", err)
+		}
+		return
+	}
+
 	cfg := config.NewDefaultConfig()
 	err := cfg.Load("./config/config.yaml")
 	if err != nil {
 		log.Fatalf("failed to read config file: %v", err)
 	}
-	log.Printf("using configuration: %#v", cfg)
+
+	err = logging.Configure(cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("failed to configure logging: %v", err)
+	}
+	log.Debugf("using configuration: %#v", cfg.Redacted())
+
+	// serve probes as soon as the config is loaded, readiness fails until clients are initialized
+	var initialized int32
+	probes := health.NewProbes()
+	probes.AddReadinessCheck("config", func() error {
+		if atomic.LoadInt32(&initialized) == 0 {
+			return errors.New("configuration is not applied yet")
+		}
+		return nil
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", probes.LivenessHandler())
+	mux.Handle("/readyz", probes.ReadinessHandler())
+	go func() {
+		err := http.ListenAndServe(cfg.ListenAddress, mux)
+		if err != nil {
+			log.Fatalf("failed to serve http on %s: %v", cfg.ListenAddress, err)
+		}
+	}()
 
 	ph, err := peakhour.NewClient(cfg.PeakHourRanges)
 	if err != nil {
 		log.Fatalf("failed to parse peak hour: %v", err)
 	}
 
-	clusterClient, err := cluster.NewClient(cfg)
+	p, err := provider.New(cfg.Provider, provider.Options{
+		InstanceAction: cfg.InstanceAction,
+		InstanceLookup: cfg.NodeAgeSource == cluster.AgeSourceProvider,
+		ScaleUp:        cfg.Surge == config.SurgeProvider,
+	})
+	if err != nil {
+		log.Fatalf("failed to init %s provider: %v", cfg.Provider, err)
+	}
+
+	clusterClient, err := cluster.NewClient(cfg, p)
 	if err != nil {
 		log.Fatalf("failed to init kubernetes client: %v", err)
 	}
 
-	schedulerClient := scheduler.NewClient(clusterClient, ph, cfg.GracefulPeriod)
+	notifier, err := notify.NewNotifier(cfg.Webhooks)
+	if err != nil {
+		log.Fatalf("failed to init webhooks: %v", err)
+	}
+	clusterClient.Notifier = notifier
+
+	if len(cfg.Hooks) > 0 {
+		hooks, err := hook.NewRunner(cfg.Hooks)
+		if err != nil {
+			log.Fatalf("failed to init hooks: %v", err)
+		}
+		clusterClient.Hooks = hooks
+	}
 
-	gracefulShutdown := make(chan os.Signal)
+	probes.AddReadinessCheck("kubernetes", clusterClient.Ping)
+	atomic.StoreInt32(&initialized, 1)
+
+	gracefulShutdown := make(chan os.Signal, 1)
 	signal.Notify(gracefulShutdown, syscall.SIGTERM, syscall.SIGINT)
 	waitGroup := &sync.WaitGroup{}
 
+	if *mode == modeAgent {
+		nodeName := os.Getenv("NODE_NAME")
+		if nodeName == "" {
+			log.Fatalf("NODE_NAME is required in agent mode")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		preemptionAgent := agent.NewAgent(clusterClient, nodeName)
+		go func() {
+			err := preemptionAgent.Run(ctx)
+			if err != nil {
+				log.Errorf("failed to handle preemption: %v", err)
+			}
+		}()
+
+		signalReceived := <-gracefulShutdown
+		log.Infof("received signal %v", signalReceived)
+		return
+	}
+
+	schedulerClient := scheduler.NewClient(clusterClient, ph, cfg.GracefulPeriod)
+	schedulerClient.MaxLifetime = provider.GetMaxLifetime(p, time.Duration(cfg.MaxLifetime)*time.Minute)
+	schedulerClient.PoolLabel = p.PoolLabel()
+	schedulerClient.ZoneLabel = cfg.ZoneLabel
+	schedulerClient.MaxDisruptedPerZone = cfg.MaxDisruptedPerZone
+	for _, scorer := range cfg.NodeScorers {
+		weighted, err := scheduler.NewWeightedScorer(scorer.Name, scorer.Weight)
+		if err != nil {
+			log.Fatalf("failed to create node scorer: %v", err)
+		}
+		schedulerClient.Scorers = append(schedulerClient.Scorers, weighted)
+	}
+	schedulerClient.EvictionNotice = time.Duration(cfg.EvictionNotice) * time.Minute
+	schedulerClient.Notifier = notifier
+
+	auditLog, err := audit.Open(cfg.AuditLog)
+	if err != nil {
+		log.Fatalf("failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+	schedulerClient.Audit = auditLog
+
+	err = schedulerClient.RestorePause()
+	if err != nil {
+		log.Fatalf("failed to restore pause state: %v", err)
+	}
+	var approvals api.Approvals
+	if cfg.IsApprovalEnabled() {
+		gate := approval.NewGate(time.Duration(cfg.ApprovalTimeout)*time.Minute, cfg.ApprovalTimeoutPolicy)
+		clusterClient.Approver = gate
+		clusterClient.ApprovalTimeout = gate.Timeout
+		approvals = gate
+	}
+	schedulerClient.ProcessTimeout = clusterClient.MaxProcessDuration()
+	api.Register(mux, schedulerClient, approvals, cfg.AdminToken)
+	schedulerClient.RegisterMetrics()
+	schedulerClient.Heartbeat = health.NewHeartbeat(heartbeatTolerance)
+	probes.AddLivenessCheck("scheduler", schedulerClient.Heartbeat.Check)
+
+	go schedulerClient.StartPreemptionCleanup(preemptionCleanupInterval)
+
 	go func(waitGroup *sync.WaitGroup) {
 		if !cfg.Debug {
+			schedulerClient.Heartbeat.Beat("start", 1*time.Minute)
 			time.Sleep(1 * time.Minute)
 		}
 
@@ -46,7 +199,50 @@ func main() {
 	}(waitGroup)
 
 	signalReceived := <-gracefulShutdown
-	log.Printf("received signal %v", signalReceived)
+	log.Infof("received signal %v", signalReceived)
+	clusterClient.InterruptWaits()
 	waitGroup.Wait()
-	log.Printf("shutting down...")
+	log.Info("shutting down...")
+}
+
+// searchAudit print audit records matching the filters, reading the files in args or stdin
+func searchAudit(args []string, node string, since string, until string) error {
+	filter := audit.Filter{Node: node}
+	var err error
+	if since != "" {
+		filter.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return err
+		}
+	}
+
+	if until != "" {
+		filter.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(args) == 0 {
+		return audit.Search(os.Stdin, os.Stdout, filter)
+	}
+
+	for _, path := range args {
+		err = searchAuditFile(path, os.Stdout, filter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func searchAuditFile(path string, w io.Writer, filter audit.Filter) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return audit.Search(file, w, filter)
 }
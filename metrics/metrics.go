@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const (
+	namespace = "preemptible_scheduler"
+
+	TransitionCordoned = "cordoned"
+	TransitionDraining = "draining"
+	TransitionDeleted  = "deleted"
+
+	ReasonBlockedByPDB = "blocked-by-pdb"
+	ReasonError        = "error"
+)
+
+var (
+	NodeAge = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "node_age_hours",
+		Help:      "Age of managed nodes observed on every scan.",
+		Buckets:   []float64{1, 2, 4, 8, 12, 16, 20, 22, 23, 24},
+	})
+
+	NodeTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "node_transitions_total",
+		Help:      "Nodes entering a recycling step: cordoned, draining or deleted.",
+	}, []string{"state"})
+
+	NodesProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "nodes_processed_total",
+		Help:      "Nodes processed by outcome.",
+	}, []string{"outcome"})
+
+	DrainDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "drain_duration_seconds",
+		Help:      "Time taken to evict all pods of a node.",
+		Buckets:   prometheus.ExponentialBuckets(5, 2, 10),
+	})
+
+	PodsEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pods_evicted_total",
+		Help:      "Pods evicted from recycled nodes.",
+	})
+
+	EvictionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "eviction_failures_total",
+		Help:      "Failed pod evictions by reason.",
+	}, []string{"reason"})
+
+	NodesExpiredInPeak = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "nodes_expired_in_peak_total",
+		Help:      "Nodes preempted by the cloud provider inside a peak hour period.",
+	})
+
+	HealthGateTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "health_gate_timeouts_total",
+		Help:      "Recycled nodes whose evicted workloads were not available again in time.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		NodeAge,
+		NodeTransitions,
+		NodesProcessed,
+		DrainDuration,
+		PodsEvicted,
+		EvictionFailures,
+		NodesExpiredInPeak,
+		HealthGateTimeouts,
+	)
+}
+
+// NewGaugeFunc create a gauge in the scheduler namespace whose value is computed on every scrape
+func NewGaugeFunc(name string, help string, labels prometheus.Labels, f func() float64) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: labels,
+	}, f)
+}
+
+// MustRegister register collectors owned by other packages to the default registry
+func MustRegister(collectors ...prometheus.Collector) {
+	prometheus.MustRegister(collectors...)
+}
+
+// Handler serve metrics of the default registry in prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
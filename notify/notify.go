@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"preemptible-lifecycle-scheduler/config"
+	"preemptible-lifecycle-scheduler/logging"
+	"text/template"
+	"time"
+)
+
+const (
+	EventDrainStarted      = "drain-started"
+	EventDrainCompleted    = "drain-completed"
+	EventDrainFailed       = "drain-failed"
+	EventNodeExpiredInPeak = "node-expired-in-peak"
+	EventSchedulerPaused   = "scheduler-paused"
+
+	EventWorkloadsUnavailable = "workloads-unavailable"
+
+	defaultRetries = 3
+)
+
+var (
+	// RetryInterval is doubled after every failed attempt
+	RetryInterval = 1 * time.Second
+	SendTimeout   = 10 * time.Second
+)
+
+// Event is a lifecycle event rendered into webhook payloads
+type Event struct {
+	Type          string    `json:"type"`
+	Node          string    `json:"node,omitempty"`
+	Time          time.Time `json:"time"`
+	Message       string    `json:"message"`
+	Step          string    `json:"step,omitempty"`
+	Outcome       string    `json:"outcome,omitempty"`
+	PodsEvicted   int       `json:"podsEvicted,omitempty"`
+	PodsRemaining int       `json:"podsRemaining,omitempty"`
+}
+
+var knownEvents = map[string]struct{}{
+	EventDrainStarted:         {},
+	EventDrainCompleted:       {},
+	EventDrainFailed:          {},
+	EventNodeExpiredInPeak:    {},
+	EventSchedulerPaused:      {},
+	EventWorkloadsUnavailable: {},
+}
+
+type target struct {
+	config.Webhook
+	events   map[string]struct{}
+	template *template.Template
+	retries  int
+}
+
+// Notifier send events to every webhook subscribed to them, in the background
+type Notifier struct {
+	HTTPClient *http.Client
+
+	targets []*target
+}
+
+// NewNotifier compile webhook templates, a webhook without events receives all of them and a webhook
+// without template receives the event as json. Failed requests are retried 3 times unless retries is set,
+// 0 included.
+func NewNotifier(webhooks []config.Webhook) (*Notifier, error) {
+	targets := make([]*target, 0)
+	for _, webhook := range webhooks {
+		t := &target{
+			Webhook: webhook,
+			events:  make(map[string]struct{}),
+			retries: defaultRetries,
+		}
+		for _, event := range webhook.Events {
+			if _, ok := knownEvents[event]; !ok {
+				return nil, fmt.Errorf("unknown event %q of webhook %s", event, webhook.Name)
+			}
+			t.events[event] = struct{}{}
+		}
+
+		if webhook.Template != "" {
+			tmpl, err := template.New(webhook.Name).Funcs(template.FuncMap{"json": toJSON}).Parse(webhook.Template)
+			if err != nil {
+				return nil, fmt.Errorf("invalid template of webhook %s: %v", webhook.Name, err)
+			}
+			t.template = tmpl
+		}
+
+		if webhook.Retries != nil {
+			if *webhook.Retries < 0 {
+				return nil, fmt.Errorf("invalid retries of webhook %s: %d", webhook.Name, *webhook.Retries)
+			}
+			t.retries = *webhook.Retries
+		}
+
+		targets = append(targets, t)
+	}
+
+	return &Notifier{
+		HTTPClient: &http.Client{Timeout: SendTimeout},
+		targets:    targets,
+	}, nil
+}
+
+// Notify send the event to subscribed webhooks without waiting for them
+func (n *Notifier) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, t := range n.targets {
+		if !t.isSubscribed(event.Type) {
+			continue
+		}
+
+		go func(t *target) {
+			err := n.send(t, event)
+			if err != nil {
+				logging.Node(event.Node, "").WithField(logging.FieldAction, "notify").
+					Errorf("failed to send %s to webhook %s: %v", event.Type, t.Name, err)
+			}
+		}(t)
+	}
+}
+
+func (t *target) isSubscribed(eventType string) bool {
+	if len(t.events) == 0 {
+		return true
+	}
+
+	_, ok := t.events[eventType]
+	return ok
+}
+
+func (t *target) render(event Event) ([]byte, error) {
+	if t.template == nil {
+		return json.Marshal(event)
+	}
+
+	var buf bytes.Buffer
+	err := t.template.Execute(&buf, event)
+	return buf.Bytes(), err
+}
+
+// send post the rendered event, retrying on errors and non 2xx responses
+func (n *Notifier) send(t *target, event Event) error {
+	body, err := t.render(event)
+	if err != nil {
+		return err
+	}
+
+	interval := RetryInterval
+	for attempt := 0; ; attempt++ {
+		err = n.post(t, body)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= t.retries {
+			return err
+		}
+
+		log.WithField(logging.FieldAction, "notify").Debugf("webhook %s attempt %d failed: %v", t.Name, attempt+1, err)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (n *Notifier) post(t *target, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// toJSON is available in templates to quote values, e.g. {"text": {{ json .Message }}}
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"preemptible-lifecycle-scheduler/config"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookStub stand in for a chat endpoint, it fails the first requests and records the bodies it accepts
+type webhookStub struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	bodies   chan string
+}
+
+func newWebhookStub(failures int) (*webhookStub, *httptest.Server) {
+	stub := &webhookStub{failures: failures, bodies: make(chan string, 10)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		stub.attempts++
+		fail := stub.attempts <= stub.failures
+		stub.mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		stub.bodies <- r.Header.Get("X-Token") + " " + string(body)
+	}))
+
+	return stub, server
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	tests := map[string]struct {
+		Webhook  config.Webhook
+		Failures int
+		Expected string
+	}{
+		"template": {
+			Webhook: config.Webhook{
+				Name:     "chat",
+				Template: `{"text": {{ json (printf "%s: node %s" .Type .Node) }}}`,
+				Headers:  map[string]string{"X-Token": "secret"},
+			},
+			Expected: `secret {"text": "drain-failed: node node-a"}`,
+		},
+		"default payload after retries": {
+			Webhook:  config.Webhook{Name: "in-house", Retries: intPtr(2)},
+			Failures: 2,
+			Expected: ` {"type":"drain-failed","node":"node-a","time":"2020-01-01T10:00:00Z","message":"drain timed out"}`,
+		},
+	}
+
+	RetryInterval = time.Millisecond
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			stub, server := newWebhookStub(tc.Failures)
+			defer server.Close()
+
+			tc.Webhook.URL = server.URL
+			tc.Webhook.Events = []string{EventDrainFailed}
+			notifier, err := NewNotifier([]config.Webhook{tc.Webhook})
+			if err != nil {
+				t.Fatalf("failed to create notifier: %v", err)
+			}
+
+			// not subscribed
+			notifier.Notify(Event{Type: EventDrainStarted, Node: "node-a"})
+			notifier.Notify(Event{
+				Type:    EventDrainFailed,
+				Node:    "node-a",
+				Time:    time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC),
+				Message: "drain timed out",
+			})
+
+			select {
+			case body := <-stub.bodies:
+				if body != tc.Expected {
+					t.Errorf("expected %v, got %v", tc.Expected, body)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("webhook was not called")
+			}
+
+			select {
+			case body := <-stub.bodies:
+				t.Errorf("expected a single notification, got %v", body)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestNotifier_Notify_NoRetries(t *testing.T) {
+	RetryInterval = time.Millisecond
+	stub, server := newWebhookStub(1)
+	defer server.Close()
+
+	notifier, err := NewNotifier([]config.Webhook{{Name: "chat", URL: server.URL, Retries: intPtr(0)}})
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+
+	err = notifier.send(notifier.targets[0], Event{Type: EventDrainFailed})
+	if err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.attempts != 1 {
+		t.Errorf("expected %v, got %v", 1, stub.attempts)
+	}
+}
+
+func TestNewNotifier_Invalid(t *testing.T) {
+	tests := map[string]config.Webhook{
+		"template":         {Name: "chat", Template: "{{ .Node "},
+		"event":            {Name: "chat", Events: []string{"drain-finished"}},
+		"negative retries": {Name: "chat", Retries: intPtr(-1)},
+	}
+
+	for name, webhook := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewNotifier([]config.Webhook{webhook})
+			if err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
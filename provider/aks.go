@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"preemptible-lifecycle-scheduler/compute"
+	"time"
+)
+
+const (
+	AKSLabelScaleSetPriority = "kubernetes.azure.com/scalesetpriority"
+	AKSLabelAgentPool        = "kubernetes.azure.com/agentpool"
+)
+
+// AKS manage spot node pools backed by virtual machine scale sets, spot instances have no lifetime limit
+type AKS struct {
+	compute computeClient
+}
+
+func NewAKS(opts Options) (*AKS, error) {
+	p := &AKS{}
+	if !opts.IsComputeEnabled() {
+		return p, nil
+	}
+
+	vmss, err := compute.NewVMSSClient(opts.InstanceAction)
+	if err != nil {
+		return nil, err
+	}
+	p.compute = vmss
+
+	return p, nil
+}
+
+func (p *AKS) Name() string {
+	return NameAKS
+}
+
+func (p *AKS) NodeSelectors() []string {
+	return []string{AKSLabelScaleSetPriority + "=spot"}
+}
+
+func (p *AKS) PoolLabel() string {
+	return AKSLabelAgentPool
+}
+
+func (p *AKS) MaxLifetime() time.Duration {
+	return 0
+}
+
+func (p *AKS) TerminateInstance(ctx context.Context, providerID string) error {
+	return terminateInstance(p.compute, ctx, providerID)
+}
+
+func (p *AKS) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	return instanceCreatedTime(p.compute, ctx, providerID)
+}
+
+func (p *AKS) ScaleUp(ctx context.Context, providerID string) error {
+	return scaleUp(p.compute, ctx, providerID)
+}
+
+func (p *AKS) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	return instanceStopped(p.compute, ctx, providerID)
+}
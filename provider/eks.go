@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"preemptible-lifecycle-scheduler/compute"
+	"time"
+)
+
+const (
+	EKSLabelCapacityType = "eks.amazonaws.com/capacityType"
+	EKSLabelNodeGroup    = "eks.amazonaws.com/nodegroup"
+)
+
+// EKS manage spot capacity of managed node groups, spot instances have no lifetime limit
+type EKS struct {
+	compute computeClient
+}
+
+func NewEKS(opts Options) (*EKS, error) {
+	p := &EKS{}
+	if !opts.IsComputeEnabled() {
+		return p, nil
+	}
+
+	asg, err := compute.NewASGClient(opts.InstanceAction)
+	if err != nil {
+		return nil, err
+	}
+	p.compute = asg
+
+	return p, nil
+}
+
+func (p *EKS) Name() string {
+	return NameEKS
+}
+
+func (p *EKS) NodeSelectors() []string {
+	return []string{EKSLabelCapacityType + "=SPOT"}
+}
+
+func (p *EKS) PoolLabel() string {
+	return EKSLabelNodeGroup
+}
+
+func (p *EKS) MaxLifetime() time.Duration {
+	return 0
+}
+
+func (p *EKS) TerminateInstance(ctx context.Context, providerID string) error {
+	return terminateInstance(p.compute, ctx, providerID)
+}
+
+func (p *EKS) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	return instanceCreatedTime(p.compute, ctx, providerID)
+}
+
+func (p *EKS) ScaleUp(ctx context.Context, providerID string) error {
+	return scaleUp(p.compute, ctx, providerID)
+}
+
+func (p *EKS) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	return instanceStopped(p.compute, ctx, providerID)
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"preemptible-lifecycle-scheduler/compute"
+	"time"
+)
+
+const (
+	GKELabelPreemptible = "cloud.google.com/gke-preemptible"
+	GKELabelSpot        = "cloud.google.com/gke-spot"
+	GKELabelNodePool    = "cloud.google.com/gke-nodepool"
+
+	gkePreemptibleLifetime = 24 * time.Hour
+)
+
+// GKE manage preemptible VM node pools, preemptible VMs are always stopped after 24 hours
+type GKE struct {
+	compute computeClient
+}
+
+func NewGKE(opts Options) (*GKE, error) {
+	p := &GKE{}
+	if !opts.IsComputeEnabled() {
+		return p, nil
+	}
+
+	gce, err := compute.NewGCEClient(context.Background(), opts.InstanceAction)
+	if err != nil {
+		return nil, err
+	}
+	p.compute = gce
+
+	return p, nil
+}
+
+func (p *GKE) Name() string {
+	return NameGKE
+}
+
+func (p *GKE) NodeSelectors() []string {
+	return []string{GKELabelPreemptible + "=true"}
+}
+
+func (p *GKE) PoolLabel() string {
+	return GKELabelNodePool
+}
+
+func (p *GKE) MaxLifetime() time.Duration {
+	return gkePreemptibleLifetime
+}
+
+func (p *GKE) TerminateInstance(ctx context.Context, providerID string) error {
+	return terminateInstance(p.compute, ctx, providerID)
+}
+
+func (p *GKE) InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error) {
+	return instanceCreatedTime(p.compute, ctx, providerID)
+}
+
+func (p *GKE) ScaleUp(ctx context.Context, providerID string) error {
+	return scaleUp(p.compute, ctx, providerID)
+}
+
+func (p *GKE) InstanceStopped(ctx context.Context, providerID string) (bool, error) {
+	return instanceStopped(p.compute, ctx, providerID)
+}
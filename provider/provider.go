@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	NameGKE = "gke"
+	NameEKS = "eks"
+	NameAKS = "aks"
+
+	// DefaultMaxLifetime is used to recycle nodes of providers without a hard instance lifetime
+	DefaultMaxLifetime = 24 * time.Hour
+)
+
+var ErrComputeDisabled = errors.New("compute api is not enabled for the provider")
+
+// Provider hide cloud specific details of preemptible or spot node pools
+type Provider interface {
+	Name() string
+	// NodeSelectors return label selectors matching preemptible or spot nodes
+	NodeSelectors() []string
+	// PoolLabel return node label holding the node pool name
+	PoolLabel() string
+	// MaxLifetime return how long an instance can run before the cloud reclaims it, 0 when there is no limit
+	MaxLifetime() time.Duration
+	// TerminateInstance recreate or delete the instance backing a node, identified by node spec.providerID
+	TerminateInstance(ctx context.Context, providerID string) error
+	// InstanceCreatedTime return the creation time of the instance backing a node
+	InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error)
+	// ScaleUp add one instance to the node group of the instance backing a node
+	ScaleUp(ctx context.Context, providerID string) error
+	// InstanceStopped return true when the cloud stopped or removed the instance backing a node
+	InstanceStopped(ctx context.Context, providerID string) (bool, error)
+}
+
+type Options struct {
+	// InstanceAction is passed to the compute client, empty when instances are not terminated by the scheduler
+	InstanceAction string
+	// InstanceLookup enable instance lookup through the provider api
+	InstanceLookup bool
+	// ScaleUp enable scaling node groups up through the provider api
+	ScaleUp bool
+}
+
+// IsComputeEnabled return true when provider needs a compute api client
+func (o Options) IsComputeEnabled() bool {
+	return o.InstanceAction != "" || o.InstanceLookup || o.ScaleUp
+}
+
+type computeClient interface {
+	TerminateInstance(ctx context.Context, providerID string) error
+	InstanceCreatedTime(ctx context.Context, providerID string) (time.Time, error)
+	ScaleUp(ctx context.Context, providerID string) error
+	InstanceStopped(ctx context.Context, providerID string) (bool, error)
+}
+
+// New create provider by name
+func New(name string, opts Options) (Provider, error) {
+	switch name {
+	case NameGKE, "":
+		return NewGKE(opts)
+	case NameEKS:
+		return NewEKS(opts)
+	case NameAKS:
+		return NewAKS(opts)
+	}
+
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// GetMaxLifetime return configured lifetime if set, otherwise the provider instance lifetime
+// or DefaultMaxLifetime for providers without one.
+func GetMaxLifetime(p Provider, configured time.Duration) time.Duration {
+	if configured > 0 {
+		return configured
+	}
+
+	if p.MaxLifetime() > 0 {
+		return p.MaxLifetime()
+	}
+
+	return DefaultMaxLifetime
+}
+
+func terminateInstance(compute computeClient, ctx context.Context, providerID string) error {
+	if compute == nil {
+		return ErrComputeDisabled
+	}
+
+	return compute.TerminateInstance(ctx, providerID)
+}
+
+func instanceCreatedTime(compute computeClient, ctx context.Context, providerID string) (time.Time, error) {
+	if compute == nil {
+		return time.Time{}, ErrComputeDisabled
+	}
+
+	return compute.InstanceCreatedTime(ctx, providerID)
+}
+
+func scaleUp(compute computeClient, ctx context.Context, providerID string) error {
+	if compute == nil {
+		return ErrComputeDisabled
+	}
+
+	return compute.ScaleUp(ctx, providerID)
+}
+
+func instanceStopped(compute computeClient, ctx context.Context, providerID string) (bool, error) {
+	if compute == nil {
+		return false, ErrComputeDisabled
+	}
+
+	return compute.InstanceStopped(ctx, providerID)
+}
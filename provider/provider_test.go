@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		Name              string
+		ExpectedSelectors []string
+		ExpectedPoolLabel string
+		ExpectedErr       bool
+	}{
+		"default": {
+			Name:              "",
+			ExpectedSelectors: []string{"cloud.google.com/gke-preemptible=true"},
+			ExpectedPoolLabel: "cloud.google.com/gke-nodepool",
+		},
+		"eks": {
+			Name:              NameEKS,
+			ExpectedSelectors: []string{"eks.amazonaws.com/capacityType=SPOT"},
+			ExpectedPoolLabel: "eks.amazonaws.com/nodegroup",
+		},
+		"aks": {
+			Name:              NameAKS,
+			ExpectedSelectors: []string{"kubernetes.azure.com/scalesetpriority=spot"},
+			ExpectedPoolLabel: "kubernetes.azure.com/agentpool",
+		},
+		"unknown": {
+			Name:        "digitalocean",
+			ExpectedErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(tc.Name, Options{})
+			if (err != nil) != tc.ExpectedErr {
+				t.Fatalf("expected error %v, got %v", tc.ExpectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			selectors := p.NodeSelectors()
+			if len(selectors) != len(tc.ExpectedSelectors) || selectors[0] != tc.ExpectedSelectors[0] {
+				t.Errorf("expected %v, got %v", tc.ExpectedSelectors, selectors)
+			}
+
+			if p.PoolLabel() != tc.ExpectedPoolLabel {
+				t.Errorf("expected %v, got %v", tc.ExpectedPoolLabel, p.PoolLabel())
+			}
+		})
+	}
+}
+
+func TestGetMaxLifetime(t *testing.T) {
+	tests := map[string]struct {
+		Provider   Provider
+		Configured time.Duration
+		Expected   time.Duration
+	}{
+		"gke preemptible": {
+			Provider: &GKE{},
+			Expected: 24 * time.Hour,
+		},
+		"eks spot, default": {
+			Provider: &EKS{},
+			Expected: DefaultMaxLifetime,
+		},
+		"configured": {
+			Provider:   &AKS{},
+			Configured: 12 * time.Hour,
+			Expected:   12 * time.Hour,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := GetMaxLifetime(tc.Provider, tc.Configured)
+			if result != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
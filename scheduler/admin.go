@@ -0,0 +1,279 @@
+package scheduler
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"preemptible-lifecycle-scheduler/audit"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/notify"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"time"
+)
+
+const (
+	// pauseCheckInterval bound how long a paused loop sleeps between checks of the pause deadline
+	pauseCheckInterval = 1 * time.Minute
+
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+var (
+	ErrNodeInFlight   = errors.New("node is already being processed")
+	ErrNodeNotManaged = errors.New("node is not managed by the scheduler")
+)
+
+// InFlightNode is a node being processed right now
+type InFlightNode struct {
+	Node      string    `json:"node"`
+	Zone      string    `json:"zone,omitempty"`
+	Reason    string    `json:"reason"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// RestorePause load the pause state persisted by a previous run
+func (c *Client) RestorePause() error {
+	state, err := c.Cluster.LoadPauseState()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.pause = state
+	c.mu.Unlock()
+
+	if state.IsActive(peakhour.Now()) {
+		log.WithField(logging.FieldAction, ActionPause).Warnf("scheduler is paused until %s: %s", formatUntil(state.Until), state.Reason)
+	}
+	return nil
+}
+
+// Pause stop processing nodes until Resume is called or until the time when it is not zero
+func (c *Client) Pause(until time.Time, reason string) error {
+	state := cluster.PauseState{Paused: true, Until: until, Reason: reason}
+	err := c.Cluster.SavePauseState(state)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.pause = state
+	c.mu.Unlock()
+
+	log.WithField(logging.FieldAction, ActionPause).Warnf("scheduler paused until %s: %s", formatUntil(until), reason)
+	c.notify(notify.Event{
+		Type:    notify.EventSchedulerPaused,
+		Message: "scheduler paused until " + formatUntil(until) + ": " + reason,
+	})
+	c.wakeUp()
+	return nil
+}
+
+func (c *Client) Resume() error {
+	err := c.Cluster.SavePauseState(cluster.PauseState{})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.pause = cluster.PauseState{}
+	c.mu.Unlock()
+
+	log.WithField(logging.FieldAction, ActionResume).Info("scheduler resumed")
+	c.wakeUp()
+	return nil
+}
+
+func (c *Client) GetPauseState() cluster.PauseState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pause
+}
+
+func (c *Client) IsPaused() bool {
+	return c.GetPauseState().IsActive(peakhour.Now())
+}
+
+// RecycleNode process a managed node right away in the background, regardless of its age and peak hour
+func (c *Client) RecycleNode(nodeName string) error {
+	nodes, err := c.Cluster.GetPreemptibleNodes()
+	if err != nil {
+		return err
+	}
+
+	var node *corev1.Node
+	for i := range nodes.Items {
+		if nodes.Items[i].Name == nodeName {
+			node = &nodes.Items[i]
+			break
+		}
+	}
+	if node == nil {
+		return ErrNodeNotManaged
+	}
+
+	reason := "requested through admin api"
+	if !c.startProcessing(node.Name, c.zoneOf(*node), reason) {
+		return ErrNodeInFlight
+	}
+
+	go func() {
+		result, _ := c.Cluster.ProcessNode(node, reason)
+		c.finishProcessing(node.Name)
+		reportResult(result)
+
+		createdAt := c.Cluster.GetNodeCreatedTime(*node)
+		c.recordDecision(audit.Record{
+			Node:      node.Name,
+			CreatedAt: createdAt,
+			ExpiredAt: createdAt.Add(c.MaxLifetime),
+			PeakState: c.GetPeakHourState(),
+			Rule:      audit.RuleRecycleRequested,
+			Action:    ActionRecycle,
+		}, result)
+	}()
+
+	return nil
+}
+
+// SkipNode leave the node alone for the rest of its lifetime
+func (c *Client) SkipNode(nodeName string) error {
+	err := c.Cluster.SkipNode(nodeName)
+	if err != nil {
+		return err
+	}
+
+	logging.Node(nodeName, "").WithField(logging.FieldAction, ActionSkip).Info("node is skipped for its lifetime")
+	return nil
+}
+
+// GetInFlightNodes return nodes being processed, either by the main loop or through the admin api
+func (c *Client) GetInFlightNodes() []InFlightNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	nodes := make([]InFlightNode, 0)
+	for _, node := range c.inFlight {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// recycleNode process the node on behalf of the main loop, keeping track of it as in-flight. False is returned
+// when the node is processed already, most likely through the admin api.
+func (c *Client) recycleNode(node corev1.Node, reason string) (*cluster.Result, bool) {
+	if !c.startProcessing(node.Name, c.zoneOf(node), reason) {
+		return nil, false
+	}
+	defer c.finishProcessing(node.Name)
+
+	c.beat("process node "+node.Name, c.processTimeout())
+	result, _ := c.Cluster.ProcessNode(&node, reason)
+	return result, true
+}
+
+// skipInFlight record a node left alone by the main loop because it is processed already, it is neither
+// a failure nor retried
+func (c *Client) skipInFlight(logger *log.Entry, decision audit.Record) {
+	logger.WithField(logging.FieldAction, ActionSkip).Info("node is already being processed")
+	decision.Rule = audit.RuleInFlight
+	decision.Action = ActionSkip
+	c.recordDecision(decision, nil)
+}
+
+// processTimeout return how long processing a node may take at most
+func (c *Client) processTimeout() time.Duration {
+	if c.ProcessTimeout <= 0 {
+		return c.GracefulPeriod
+	}
+	return c.ProcessTimeout
+}
+
+func (c *Client) startProcessing(nodeName string, zone string, reason string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.inFlight[nodeName]; ok {
+		return false
+	}
+
+	c.inFlight[nodeName] = InFlightNode{Node: nodeName, Zone: zone, Reason: reason, StartedAt: peakhour.Now()}
+	return true
+}
+
+func (c *Client) finishProcessing(nodeName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inFlight, nodeName)
+}
+
+// isSkipped tell whether the main loop should leave the node alone, and the audit rule why
+func (c *Client) isSkipped(node corev1.Node) (bool, string) {
+	if cluster.IsNodeSkipped(&node) {
+		return true, audit.RuleSkippedByOperator
+	}
+
+	if c.IsPaused() {
+		return true, audit.RulePaused
+	}
+
+	return false, ""
+}
+
+// waitWhilePaused sleep while the scheduler is paused, return true when it slept
+func (c *Client) waitWhilePaused() bool {
+	state := c.GetPauseState()
+	now := peakhour.Now()
+	if !state.IsActive(now) {
+		return false
+	}
+
+	d := pauseCheckInterval
+	if !state.Until.IsZero() && state.Until.Sub(now) < d {
+		d = state.Until.Sub(now)
+	}
+
+	log.WithField(logging.FieldAction, ActionPause).Debugf("scheduler is paused until %s", formatUntil(state.Until))
+	c.sleep(d)
+	return true
+}
+
+// sleep wait for the duration or until woken up by the admin api
+func (c *Client) sleep(d time.Duration) {
+	c.beat("sleep", d)
+
+	c.mu.Lock()
+	c.nextWakeup = peakhour.Now().Add(d)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.wake:
+	}
+}
+
+func (c *Client) wakeUp() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Client) notify(event notify.Event) {
+	if c.Notifier == nil {
+		return
+	}
+
+	c.Notifier.Notify(event)
+}
+
+func formatUntil(until time.Time) string {
+	if until.IsZero() {
+		return "resumed"
+	}
+
+	return until.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/audit"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"testing"
+	"time"
+)
+
+func TestClient_Pause(t *testing.T) {
+	now := time.Date(1, 1, 2, 7, 0, 0, 0, time.Now().Location())
+	peakhour.Now = func() time.Time {
+		return now
+	}
+
+	tests := map[string]struct {
+		Until    time.Time
+		Expected bool
+	}{
+		"indefinitely": {
+			Expected: true,
+		},
+		"until later": {
+			Until:    now.Add(time.Hour),
+			Expected: true,
+		},
+		"until passed": {
+			Until:    now.Add(-time.Hour),
+			Expected: false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			clusterClient := NewMockClusterClient()
+			client := NewClient(clusterClient, nil, 15)
+
+			err := client.Pause(tc.Until, "incident")
+			if err != nil {
+				t.Fatalf("failed to pause: %v", err)
+			}
+
+			if client.IsPaused() != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, client.IsPaused())
+			}
+
+			// pause survives a restart
+			restarted := NewClient(clusterClient, nil, 15)
+			err = restarted.RestorePause()
+			if err != nil {
+				t.Fatalf("failed to restore pause: %v", err)
+			}
+
+			if restarted.IsPaused() != tc.Expected {
+				t.Errorf("expected restored %v, got %v", tc.Expected, restarted.IsPaused())
+			}
+
+			err = restarted.Resume()
+			if err != nil {
+				t.Fatalf("failed to resume: %v", err)
+			}
+
+			if restarted.IsPaused() || clusterClient.Pause.Paused {
+				t.Errorf("expected resumed, got %+v", clusterClient.Pause)
+			}
+		})
+	}
+}
+
+func TestClient_isSkipped(t *testing.T) {
+	tests := map[string]struct {
+		Annotations map[string]string
+		Paused      bool
+		Expected    string
+	}{
+		"not skipped": {
+			Expected: "",
+		},
+		"skipped by operator": {
+			Annotations: map[string]string{cluster.AnnotationSkip: "2020-01-01T10:00:00Z"},
+			Expected:    audit.RuleSkippedByOperator,
+		},
+		"paused": {
+			Paused:   true,
+			Expected: audit.RulePaused,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := NewClient(NewMockClusterClient(), nil, 15)
+			if tc.Paused {
+				_ = client.Pause(time.Time{}, "")
+			}
+
+			node := corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node-a", Annotations: tc.Annotations}}
+			skipped, rule := client.isSkipped(node)
+			if skipped != (tc.Expected != "") || rule != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, rule)
+			}
+		})
+	}
+}
+
+func TestClient_recycleNode_InFlight(t *testing.T) {
+	client := NewClient(NewMockClusterClient(), nil, 15)
+	if !client.startProcessing("node-a", "", "test") {
+		t.Fatalf("expected node to start processing")
+	}
+
+	result, started := client.recycleNode(corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node-a"}}, "test")
+	if started || result != nil {
+		t.Errorf("expected node not to be processed again, got %+v", result)
+	}
+
+	client.finishProcessing("node-a")
+	if len(client.GetInFlightNodes()) != 0 {
+		t.Errorf("expected no in-flight nodes, got %v", client.GetInFlightNodes())
+	}
+}
@@ -1,30 +1,91 @@
 package scheduler
 
 import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	"log"
+	"preemptible-lifecycle-scheduler/audit"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/health"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/metrics"
 	"preemptible-lifecycle-scheduler/peakhour"
+	"preemptible-lifecycle-scheduler/provider"
+	"sync"
 	"time"
 )
 
 const (
 	peakHourMultiplier = 2
+	retryInterval      = 5 * time.Minute
+	scanTimeout        = 5 * time.Minute
 
 	InPeakHour      = "in peak hour"
 	OutsidePeakHour = "outside peak hour"
 	StartPeakHour   = "start peak hour"
+
+	ActionRecycle = "recycle"
+	ActionSkip    = "skip"
+	ActionSleep   = "sleep"
+	ActionScan    = "scan"
 )
 
 type ClusterClient interface {
 	GetPreemptibleNodes() (*corev1.NodeList, error)
-	ProcessNode(node *corev1.Node) (err error)
+	ProcessNode(node *corev1.Node, reason string) (*cluster.Result, error)
 	GetNodeCreatedTime(node corev1.Node) time.Time
+	CleanupPreemptedNodes() ([]string, error)
+	GetNode(nodeName string) (*corev1.Node, error)
+	SkipNode(nodeName string) error
+	LoadPauseState() (cluster.PauseState, error)
+	SavePauseState(state cluster.PauseState) error
+	SetEvictionTime(nodeName string, evictAt time.Time) error
+	GetEvictionTime(nodeName string) (time.Time, error)
+	SetRecyclePlan(node *corev1.Node, plannedAt time.Time, reason string, message string) error
+	GetPods(nodeName string) ([]corev1.Pod, error)
 }
 
 type Client struct {
 	Cluster        ClusterClient
 	PeakHours      *peakhour.Client
 	GracefulPeriod time.Duration
+	MaxLifetime    time.Duration
+	// ProcessTimeout is how long processing a node may take at most, GracefulPeriod when zero
+	ProcessTimeout time.Duration
+	// EvictionNotice is how long ahead pods are annotated with the time their node is recycled, 0 disables it
+	EvictionNotice time.Duration
+	// PoolLabel is the node label holding node pool name, used in logs only
+	PoolLabel string
+	// ZoneLabel is the node label holding node zone, nodes are recycled in turns across zones
+	ZoneLabel string
+	// MaxDisruptedPerZone hold nodes back while as many nodes of their zone are processed or cordoned,
+	// 0 disables the limit
+	MaxDisruptedPerZone int
+	// Scorers decide in which order due nodes are recycled, by expiry alone when empty
+	Scorers []WeightedScorer
+	// Notifier receive scheduler lifecycle events, optional
+	Notifier cluster.Notifier
+	// Audit receive every decision made about a node, optional
+	Audit *audit.Logger
+	// Heartbeat is updated before every step of the main loop, liveness fails once a step overruns
+	Heartbeat *health.Heartbeat
+
+	// nodes that failed processing in the last iteration and should be retried soon
+	retryNodes int
+	// preempted nodes found in the last cleanup
+	preemptedNodes map[string]struct{}
+	// when the eviction of a node was first announced, and when the first node held for its notice is released
+	announcedAt     map[string]time.Time
+	noticeHeldUntil time.Time
+
+	// mu guard state shared with the admin and status api
+	mu         sync.Mutex
+	pause      cluster.PauseState
+	inFlight   map[string]InFlightNode
+	nextWakeup time.Time
+	wake       chan struct{}
+	// scores of the nodes of the last scan, by node name
+	scores map[string]float64
 }
 
 func NewClient(cluster ClusterClient, peakHour *peakhour.Client, gracefulPeriod int) *Client {
@@ -32,91 +93,296 @@ func NewClient(cluster ClusterClient, peakHour *peakhour.Client, gracefulPeriod
 		Cluster:        cluster,
 		PeakHours:      peakHour,
 		GracefulPeriod: peakHourMultiplier * time.Duration(gracefulPeriod) * time.Minute,
+		MaxLifetime:    provider.DefaultMaxLifetime,
+		ZoneLabel:      DefaultZoneLabel,
+		inFlight:       make(map[string]InFlightNode),
+		wake:           make(chan struct{}, 1),
 	}
 }
 
 func (c *Client) Start() {
 	for {
+		if c.waitWhilePaused() {
+			continue
+		}
+
 		currentState := c.GetPeakHourState()
-		log.Printf("current state: %s", currentState)
+		logger := log.WithField(logging.FieldState, currentState)
+		logger.Debug("current state")
 
 		switch currentState {
 		case InPeakHour:
 			sleepDuration := c.PeakHours.GetNearestEndPeakHour().Sub(peakhour.Now())
-			log.Printf("in peak hour, waiting %s", sleepDuration.String())
-			time.Sleep(sleepDuration)
+			if sleepDuration <= c.EvictionNotice {
+				c.announceAllEvictions()
+			}
+			sleepDuration = c.noticeSleep(sleepDuration)
+			logger.WithFields(log.Fields{
+				logging.FieldAction:   ActionSleep,
+				logging.FieldDeadline: logging.Deadline(peakhour.Now().Add(sleepDuration)),
+			}).Infof("in peak hour, waiting %s", sleepDuration.String())
+			c.sleep(sleepDuration)
 
 		case OutsidePeakHour:
+			c.beat("scan", scanTimeout)
 			nodes, err := c.Cluster.GetPreemptibleNodes()
 			if err != nil {
-				log.Printf("failed to get preemptible nodes: %v", err)
+				logger.WithField(logging.FieldAction, ActionScan).Errorf("failed to get preemptible nodes: %v", err)
 				break
 			}
 
 			if len(nodes.Items) == 0 {
 				continue
 			}
-			log.Printf("%d nodes found", len(nodes.Items))
+			logger.WithField(logging.FieldAction, ActionScan).Infof("%d nodes found", len(nodes.Items))
+			c.publishPlan(nodes.Items)
 
+			c.announceEvictions(nodes.Items)
 			unprocessedNodes := c.ProcessNodesOutsidePeakHour(nodes.Items)
 
 			sleepDuration := c.CalculateNextSchedule(unprocessedNodes)
-			log.Printf("waiting for next schedule: %s", sleepDuration.String())
-			time.Sleep(sleepDuration)
+			if c.retryNodes > 0 && sleepDuration > retryInterval {
+				logger.Warnf("%d nodes failed processing, retrying earlier", c.retryNodes)
+				sleepDuration = retryInterval
+			}
+			sleepDuration = c.heldSleep(c.noticeSleep(sleepDuration))
+			logger.WithFields(log.Fields{
+				logging.FieldAction:   ActionSleep,
+				logging.FieldDeadline: logging.Deadline(peakhour.Now().Add(sleepDuration)),
+			}).Infof("waiting for next schedule: %s", sleepDuration.String())
+			c.sleep(sleepDuration)
 
 		case StartPeakHour:
+			c.beat("scan", scanTimeout)
 			nodes, err := c.Cluster.GetPreemptibleNodes()
 			if err != nil {
-				log.Printf("failed to get preemptible nodes: %v", err)
+				logger.WithField(logging.FieldAction, ActionScan).Errorf("failed to get preemptible nodes: %v", err)
 				break
 			}
 
 			if len(nodes.Items) == 0 {
 				continue
 			}
-			log.Printf("%d nodes found", len(nodes.Items))
+			logger.WithField(logging.FieldAction, ActionScan).Infof("%d nodes found", len(nodes.Items))
+			c.publishPlan(nodes.Items)
 
+			c.announceEvictions(nodes.Items)
 			c.ProcessNodesStartPeakHour(nodes.Items)
 
-			sleepDuration := c.PeakHours.GetNearestEndPeakHour().Sub(peakhour.Now())
-			log.Printf("waiting for next peak hour period: %s", sleepDuration.String())
-			time.Sleep(sleepDuration)
+			sleepDuration := c.heldSleep(c.noticeSleep(c.PeakHours.GetNearestEndPeakHour().Sub(peakhour.Now())))
+			logger.WithFields(log.Fields{
+				logging.FieldAction:   ActionSleep,
+				logging.FieldDeadline: logging.Deadline(peakhour.Now().Add(sleepDuration)),
+			}).Infof("waiting for next peak hour period: %s", sleepDuration.String())
+			c.sleep(sleepDuration)
 		}
 	}
 }
 
+// nodeLogger return a log entry carrying node, pool, peak hour state and the time node expires
+func (c *Client) nodeLogger(node corev1.Node, state string, expiredAt time.Time) *log.Entry {
+	return logging.Node(node.Name, node.Labels[c.PoolLabel]).WithFields(log.Fields{
+		logging.FieldState:    state,
+		logging.FieldDeadline: logging.Deadline(expiredAt),
+	})
+}
+
+// recordDecision write the decision to the audit log, result is nil when the node was skipped
+func (c *Client) recordDecision(decision audit.Record, result *cluster.Result) {
+	if c.Audit == nil {
+		return
+	}
+
+	decision.Time = peakhour.Now()
+	decision.GracefulPeriod = c.GracefulPeriod.String()
+	if result != nil {
+		decision.Outcome = string(result.Outcome)
+		if result.Err != nil {
+			decision.Error = result.Err.Error()
+		}
+	}
+
+	err := c.Audit.Write(decision)
+	if err != nil {
+		log.WithField(logging.FieldNode, decision.Node).Errorf("failed to write audit record: %v", err)
+	}
+}
+
+// beat update the heartbeat with the step about to run and how long it may take
+func (c *Client) beat(step string, d time.Duration) {
+	if c.Heartbeat == nil {
+		return
+	}
+
+	c.Heartbeat.Beat(step, d)
+}
+
+// StartPreemptionCleanup remove pods stuck on preempted nodes every interval, it runs independently
+// of the main loop which may sleep for hours.
+func (c *Client) StartPreemptionCleanup(interval time.Duration) {
+	for {
+		preempted, err := c.Cluster.CleanupPreemptedNodes()
+		if err != nil {
+			log.WithField(logging.FieldAction, "cleanup").Errorf("failed to clean up preempted nodes: %v", err)
+		} else {
+			c.trackPreemptedNodes(preempted)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// HandleResult act on the outcome of a node processed by the main loop
+func (c *Client) HandleResult(result *cluster.Result) {
+	reportResult(result)
+	if result.IsRetryable() {
+		c.retryNodes++
+	}
+}
+
+// reportResult log the outcome of a processed node and count it
+func reportResult(result *cluster.Result) {
+	metrics.NodesProcessed.WithLabelValues(string(result.Outcome)).Inc()
+
+	logger := logging.Node(result.Node, "").WithFields(log.Fields{
+		logging.FieldAction: result.Step,
+		logging.FieldState:  result.Outcome,
+	})
+	switch result.Outcome {
+	case cluster.OutcomeDeleted:
+		logger.Infof("node deleted in %s, %d pods evicted", result.Duration, result.PodsEvicted)
+		if result.WorkloadsUnavailable > 0 {
+			logger.Warnf("%d workloads still unavailable after node deletion", result.WorkloadsUnavailable)
+		}
+	case cluster.OutcomeNodeVanished:
+		logger.Warn("node vanished before it was deleted, probably preempted")
+	case cluster.OutcomeBlockedByPDB:
+		logger.Errorf("ALERT: node drain blocked by disruption budget, %d pods remaining", result.PodsRemaining)
+	case cluster.OutcomeTimedOut:
+		logger.Errorf("ALERT: node timed out, %d pods remaining", result.PodsRemaining)
+	case cluster.OutcomeRejected:
+		logger.Warnf("node processing was not approved: %v", result.Err)
+	case cluster.OutcomeVetoed:
+		logger.Warnf("node drain was vetoed by hook, will retry: %v", result.Err)
+	case cluster.OutcomeHookFailed:
+		logger.Errorf("ALERT: node hook failed, will retry: %v", result.Err)
+	case cluster.OutcomeDeferred:
+		logger.Warnf("node deferred, will retry: %v", result.Err)
+	default:
+		logger.Errorf("ALERT: node failed: %v", result.Err)
+	}
+}
+
 func (c *Client) ProcessNodesStartPeakHour(nodes []corev1.Node) {
-	for _, node := range nodes {
+	c.retryNodes = 0
+	c.noticeHeldUntil = time.Time{}
+	ordered := c.orderNodes(nodes)
+	c.logOrder(ordered, StartPeakHour)
+	for _, scored := range ordered {
+		node := scored.Node
 		createdAt := c.Cluster.GetNodeCreatedTime(node)
-		log.Println(createdAt.String())
+		metrics.NodeAge.Observe(peakhour.Now().Sub(createdAt).Hours())
 		endPeakHour := c.PeakHours.GetNearestEndPeakHour()
 
 		// node won't survive next peak hour period
-		if endPeakHour.After(createdAt.Add(24*time.Hour)) || endPeakHour.Equal(createdAt.Add(24*time.Hour)) {
-			err := c.Cluster.ProcessNode(&node)
-			if err != nil {
-				log.Printf("failed to process node: %v", err)
+		expiredAt := createdAt.Add(c.MaxLifetime)
+		logger := c.nodeLogger(node, StartPeakHour, expiredAt)
+		decision := audit.Record{
+			Node:      node.Name,
+			CreatedAt: createdAt,
+			ExpiredAt: expiredAt,
+			PeakState: StartPeakHour,
+		}
+		if skip, rule := c.isSkipped(node); skip {
+			logger.WithField(logging.FieldAction, ActionSkip).Debugf("node is not processed: %s", rule)
+			decision.Rule = rule
+			decision.Action = ActionSkip
+			c.recordDecision(decision, nil)
+			continue
+		}
+
+		if endPeakHour.After(expiredAt) || endPeakHour.Equal(expiredAt) {
+			if c.holdForNotice(node, logger, decision) || c.holdForZone(node, nodes, logger, decision) {
+				continue
+			}
+
+			reason := fmt.Sprintf("expiring in %s, before peak hour ends at %s",
+				expiredAt.Sub(peakhour.Now()).Round(time.Minute), endPeakHour.Format("15:04"))
+			logger.WithField(logging.FieldAction, ActionRecycle).Info(reason)
+			result, started := c.recycleNode(node, reason)
+			if !started {
+				c.skipInFlight(logger, decision)
 				continue
 			}
+			c.HandleResult(result)
+
+			decision.Rule = audit.RuleExpiresBeforePeakEnd
+			decision.Action = ActionRecycle
+			c.recordDecision(decision, result)
+			continue
 		}
+
+		logger.WithField(logging.FieldAction, ActionSkip).Debugf("node created at %s survives peak hour", createdAt.Format(time.RFC3339))
+		decision.Rule = audit.RuleSurvivesPeakHour
+		decision.Action = ActionSkip
+		c.recordDecision(decision, nil)
 	}
 }
 
 func (c *Client) ProcessNodesOutsidePeakHour(nodes []corev1.Node) []corev1.Node {
+	c.retryNodes = 0
+	c.noticeHeldUntil = time.Time{}
 	unprocessedNodes := make([]corev1.Node, 0)
-	for _, node := range nodes {
+	ordered := c.orderNodes(nodes)
+	c.logOrder(ordered, OutsidePeakHour)
+	for _, scored := range ordered {
+		node := scored.Node
 		createdAt := c.Cluster.GetNodeCreatedTime(node)
-		log.Println(createdAt.String())
+		metrics.NodeAge.Observe(peakhour.Now().Sub(createdAt).Hours())
 
 		// node is nearly terminated
-		if createdAt.Add(24*time.Hour).Sub(peakhour.Now()) <= c.GracefulPeriod {
-			err := c.Cluster.ProcessNode(&node)
-			if err != nil {
-				log.Printf("failed to process node: %v", err)
+		expiredAt := createdAt.Add(c.MaxLifetime)
+		logger := c.nodeLogger(node, OutsidePeakHour, expiredAt)
+		decision := audit.Record{
+			Node:      node.Name,
+			CreatedAt: createdAt,
+			ExpiredAt: expiredAt,
+			PeakState: OutsidePeakHour,
+		}
+		if skip, rule := c.isSkipped(node); skip {
+			logger.WithField(logging.FieldAction, ActionSkip).Debugf("node is not processed: %s", rule)
+			decision.Rule = rule
+			decision.Action = ActionSkip
+			c.recordDecision(decision, nil)
+			continue
+		}
+
+		if expiredAt.Sub(peakhour.Now()) <= c.GracefulPeriod {
+			if c.holdForNotice(node, logger, decision) || c.holdForZone(node, nodes, logger, decision) {
+				continue
 			}
+
+			reason := fmt.Sprintf("expiring in %s, within graceful period of %s",
+				expiredAt.Sub(peakhour.Now()).Round(time.Minute), c.GracefulPeriod)
+			logger.WithField(logging.FieldAction, ActionRecycle).Info(reason)
+			result, started := c.recycleNode(node, reason)
+			if !started {
+				c.skipInFlight(logger, decision)
+				continue
+			}
+			c.HandleResult(result)
+
+			decision.Rule = audit.RuleExpiresWithinGraceful
+			decision.Action = ActionRecycle
+			c.recordDecision(decision, result)
 			continue
 		}
 
+		logger.WithField(logging.FieldAction, ActionSkip).Debugf("node created at %s is not expiring yet", createdAt.Format(time.RFC3339))
+		decision.Rule = audit.RuleNotExpiring
+		decision.Action = ActionSkip
+		c.recordDecision(decision, nil)
+
 		unprocessedNodes = append(unprocessedNodes, node)
 	}
 
@@ -126,7 +392,7 @@ func (c *Client) ProcessNodesOutsidePeakHour(nodes []corev1.Node) []corev1.Node
 func (c *Client) CalculateNextSchedule(nodes []corev1.Node) time.Duration {
 	minT := peakhour.Now().Add(24 * time.Hour)
 	for _, node := range nodes {
-		t := c.Cluster.GetNodeCreatedTime(node).Add(24 * time.Hour)
+		t := c.Cluster.GetNodeCreatedTime(node).Add(c.MaxLifetime)
 
 		if minT.After(t) {
 			minT = t
@@ -1,8 +1,11 @@
 package scheduler
 
 import (
+	"bytes"
+	"encoding/json"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/audit"
 	"preemptible-lifecycle-scheduler/cluster"
 	"preemptible-lifecycle-scheduler/peakhour"
 	"testing"
@@ -173,7 +176,19 @@ func TestClient_CalculateNextSchedule(t *testing.T) {
 }
 
 type MockClusterClient struct {
-	ProcessedTs []time.Time
+	ProcessedTs    []time.Time
+	ProcessedNodes []string
+	Pause          cluster.PauseState
+	// EvictionTimes record the last eviction time set per node when not nil
+	EvictionTimes map[string]time.Time
+	// RecyclePlans record the last plan reason published per node when not nil
+	RecyclePlans map[string]string
+	// Pods are the pods of every node, by node name
+	Pods map[string][]corev1.Pod
+	// PodListings count calls to GetPods
+	PodListings int
+	// Nodes are returned as preemptible nodes
+	Nodes []corev1.Node
 }
 
 func NewMockClusterClient() *MockClusterClient {
@@ -183,14 +198,59 @@ func NewMockClusterClient() *MockClusterClient {
 }
 
 func (c *MockClusterClient) GetPreemptibleNodes() (*corev1.NodeList, error) {
-	return nil, nil
+	return &corev1.NodeList{Items: c.Nodes}, nil
 }
 
-func (c *MockClusterClient) ProcessNode(node *corev1.Node) (err error) {
+func (c *MockClusterClient) ProcessNode(node *corev1.Node, reason string) (*cluster.Result, error) {
 	c.ProcessedTs = append(c.ProcessedTs, c.GetNodeCreatedTime(*node))
+	c.ProcessedNodes = append(c.ProcessedNodes, node.Name)
+	return &cluster.Result{Node: node.Name, Outcome: cluster.OutcomeDeleted, Deleted: true}, nil
+}
+
+func (c *MockClusterClient) CleanupPreemptedNodes() ([]string, error) {
+	return nil, nil
+}
+
+func (c *MockClusterClient) GetNode(nodeName string) (*corev1.Node, error) {
+	return &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: nodeName}}, nil
+}
+
+func (c *MockClusterClient) SkipNode(nodeName string) error {
+	return nil
+}
+
+func (c *MockClusterClient) LoadPauseState() (cluster.PauseState, error) {
+	return c.Pause, nil
+}
+
+func (c *MockClusterClient) SavePauseState(state cluster.PauseState) error {
+	c.Pause = state
 	return nil
 }
 
+func (c *MockClusterClient) SetEvictionTime(nodeName string, evictAt time.Time) error {
+	if c.EvictionTimes != nil {
+		c.EvictionTimes[nodeName] = evictAt
+	}
+	return nil
+}
+
+func (c *MockClusterClient) GetEvictionTime(nodeName string) (time.Time, error) {
+	return c.EvictionTimes[nodeName], nil
+}
+
+func (c *MockClusterClient) SetRecyclePlan(node *corev1.Node, plannedAt time.Time, reason string, message string) error {
+	if c.RecyclePlans != nil {
+		c.RecyclePlans[node.Name] = reason
+	}
+	return nil
+}
+
+func (c *MockClusterClient) GetPods(nodeName string) ([]corev1.Pod, error) {
+	c.PodListings++
+	return c.Pods[nodeName], nil
+}
+
 func (c *MockClusterClient) GetNodeCreatedTime(node corev1.Node) time.Time {
 	cc := &cluster.Client{}
 	return cc.GetNodeCreatedTime(node)
@@ -423,3 +483,109 @@ func TestClient_ProcessNodesOutsidePeakHour(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_HandleResult(t *testing.T) {
+	tests := map[string]struct {
+		Outcomes []cluster.Outcome
+		Expected int
+	}{
+		"all deleted": {
+			Outcomes: []cluster.Outcome{cluster.OutcomeDeleted, cluster.OutcomeNodeVanished},
+			Expected: 0,
+		},
+		"some retryable": {
+			Outcomes: []cluster.Outcome{cluster.OutcomeDeleted, cluster.OutcomeTimedOut, cluster.OutcomeBlockedByPDB},
+			Expected: 2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := NewClient(nil, nil, 15)
+			for _, outcome := range tc.Outcomes {
+				client.HandleResult(&cluster.Result{Outcome: outcome})
+			}
+
+			if client.retryNodes != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, client.retryNodes)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNodesOutsidePeakHour_Audit(t *testing.T) {
+	peakhour.Now = func() time.Time {
+		return time.Date(1, 1, 2, 10, 15, 0, 0, time.Now().Location())
+	}
+
+	ph, err := peakhour.NewClient([]string{})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	nodes := []corev1.Node{
+		{ObjectMeta: v1.ObjectMeta{Name: "expiring", CreationTimestamp: v1.Time{Time: time.Date(1, 1, 1, 10, 22, 0, 0, time.Now().Location())}}},
+		{ObjectMeta: v1.ObjectMeta{Name: "young", CreationTimestamp: v1.Time{Time: time.Date(1, 1, 2, 10, 14, 0, 0, time.Now().Location())}}},
+	}
+
+	tests := map[string]struct {
+		InFlight string
+		Expected []audit.Record
+	}{
+		"recycled": {
+			Expected: []audit.Record{
+				{Node: "expiring", Rule: audit.RuleExpiresWithinGraceful, Action: ActionRecycle, Outcome: string(cluster.OutcomeDeleted)},
+				{Node: "young", Rule: audit.RuleNotExpiring, Action: ActionSkip},
+			},
+		},
+		"already in flight": {
+			InFlight: "expiring",
+			Expected: []audit.Record{
+				{Node: "expiring", Rule: audit.RuleInFlight, Action: ActionSkip},
+				{Node: "young", Rule: audit.RuleNotExpiring, Action: ActionSkip},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			client := NewClient(NewMockClusterClient(), ph, 15)
+			client.Audit = audit.NewLogger(&buf)
+			if tc.InFlight != "" {
+				client.startProcessing(tc.InFlight, "", "test")
+			}
+			client.ProcessNodesOutsidePeakHour(nodes)
+
+			records := make([]audit.Record, 0)
+			decoder := json.NewDecoder(&buf)
+			for decoder.More() {
+				var record audit.Record
+				err = decoder.Decode(&record)
+				if err != nil {
+					t.Fatalf("failed to decode record: %v", err)
+				}
+				records = append(records, record)
+			}
+
+			if len(records) != len(tc.Expected) {
+				t.Fatalf("expected %v, got %v", tc.Expected, records)
+			}
+
+			for i, record := range records {
+				if record.Node != tc.Expected[i].Node || record.Rule != tc.Expected[i].Rule ||
+					record.Action != tc.Expected[i].Action || record.Outcome != tc.Expected[i].Outcome {
+					t.Errorf("expected %+v, got %+v", tc.Expected[i], record)
+				}
+
+				if record.PeakState != OutsidePeakHour || record.GracefulPeriod != "30m0s" {
+					t.Errorf("expected %s with 30m0s graceful period, got %+v", OutsidePeakHour, record)
+				}
+			}
+
+			if client.retryNodes != 0 {
+				t.Errorf("expected %v, got %v", 0, client.retryNodes)
+			}
+		})
+	}
+}
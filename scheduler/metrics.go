@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"preemptible-lifecycle-scheduler/metrics"
+	"preemptible-lifecycle-scheduler/notify"
+	"preemptible-lifecycle-scheduler/peakhour"
+)
+
+// RegisterMetrics expose peak hour state and time to the next transition, both are computed on scrape
+// since the main loop sleeps through the whole peak hour period.
+func (c *Client) RegisterMetrics() {
+	collectors := []prometheus.Collector{
+		metrics.NewGaugeFunc("next_transition_seconds", "Seconds until peak hour starts or ends.", nil, c.SecondsToNextTransition),
+	}
+
+	for _, state := range []string{InPeakHour, OutsidePeakHour, StartPeakHour} {
+		state := state
+		collectors = append(collectors, metrics.NewGaugeFunc("peak_state", "Current peak hour state of the scheduler.",
+			prometheus.Labels{"state": state}, func() float64 {
+				if c.GetPeakHourState() == state {
+					return 1
+				}
+				return 0
+			}))
+	}
+
+	metrics.MustRegister(collectors...)
+}
+
+// SecondsToNextTransition return time until the end of the current peak hour, or until the next one starts
+func (c *Client) SecondsToNextTransition() float64 {
+	if c.PeakHours.IsPeakHourNow() {
+		return c.PeakHours.GetNearestEndPeakHour().Sub(peakhour.Now()).Seconds()
+	}
+
+	return c.PeakHours.GetNearestStartPeakHour().Sub(peakhour.Now()).Seconds()
+}
+
+// trackPreemptedNodes count nodes newly preempted during peak hour, nodes already seen in the previous
+// cleanup are not counted twice.
+func (c *Client) trackPreemptedNodes(nodes []string) {
+	inPeakHour := c.PeakHours.IsPeakHourNow()
+	seen := make(map[string]struct{})
+	for _, node := range nodes {
+		seen[node] = struct{}{}
+		if _, ok := c.preemptedNodes[node]; ok {
+			continue
+		}
+
+		if inPeakHour {
+			metrics.NodesExpiredInPeak.Inc()
+			c.notify(notify.Event{
+				Type:    notify.EventNodeExpiredInPeak,
+				Node:    node,
+				Message: "node was preempted during peak hour",
+			})
+		}
+	}
+
+	c.preemptedNodes = seen
+}
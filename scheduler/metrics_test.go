@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"preemptible-lifecycle-scheduler/metrics"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"testing"
+	"time"
+)
+
+func TestClient_SecondsToNextTransition(t *testing.T) {
+	tests := map[string]struct {
+		CurrentTime *peakhour.Time
+		Expected    float64
+	}{
+		"in peak hour": {
+			CurrentTime: &peakhour.Time{Hour: 12, Minute: 30},
+			Expected:    (2*time.Hour + 30*time.Minute).Seconds(),
+		},
+		"outside peak hour": {
+			CurrentTime: &peakhour.Time{Hour: 9, Minute: 45},
+			Expected:    (15 * time.Minute).Seconds(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			peakhour.Now = func() time.Time {
+				return time.Date(1, 1, 1, tc.CurrentTime.Hour, tc.CurrentTime.Minute, 0, 0, time.Now().Location())
+			}
+
+			ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+			if err != nil {
+				t.Fatalf("failed to create peak hour client %v", err)
+			}
+
+			client := NewClient(nil, ph, 15)
+			result := client.SecondsToNextTransition()
+			if result != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
+
+func TestClient_trackPreemptedNodes(t *testing.T) {
+	peakhour.Now = func() time.Time {
+		return time.Date(1, 1, 1, 12, 0, 0, 0, time.Now().Location())
+	}
+
+	ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	client := NewClient(nil, ph, 15)
+	before := testutil.ToFloat64(metrics.NodesExpiredInPeak)
+
+	client.trackPreemptedNodes([]string{"node-a"})
+	client.trackPreemptedNodes([]string{"node-a", "node-b"})
+	client.trackPreemptedNodes([]string{})
+
+	result := testutil.ToFloat64(metrics.NodesExpiredInPeak) - before
+	if result != 2 {
+		t.Errorf("expected %v, got %v", 2, result)
+	}
+}
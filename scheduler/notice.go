@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"preemptible-lifecycle-scheduler/audit"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"time"
+)
+
+const ActionAnnounce = "announce"
+
+// announceEvictions annotate pods of nodes planned for recycling within EvictionNotice with the planned time,
+// pods of other nodes get the annotation cleared in case the plan changed since it was set. A node announced
+// late is evicted EvictionNotice after it was first announced, unless it would be recycled too late by then.
+// Announcements made before a restart are recovered from the annotation.
+func (c *Client) announceEvictions(nodes []corev1.Node) {
+	if c.EvictionNotice <= 0 {
+		return
+	}
+
+	now := peakhour.Now()
+	announcedAt := make(map[string]time.Time)
+	for _, node := range nodes {
+		var evictAt time.Time
+		if skip, _ := c.isSkipped(node); !skip {
+			expiredAt := c.Cluster.GetNodeCreatedTime(node).Add(c.MaxLifetime)
+			plannedAt := c.PlannedAt(expiredAt)
+			if plannedAt.Sub(now) <= c.EvictionNotice {
+				t, ok := c.announcedAt[node.Name]
+				if !ok {
+					t = c.restoreAnnouncement(node, now)
+				}
+				announcedAt[node.Name] = t
+
+				evictAt = plannedAt
+				if noticeEnd := c.noticeEnd(t, expiredAt); noticeEnd.After(evictAt) {
+					evictAt = noticeEnd
+				}
+			}
+		}
+
+		err := c.Cluster.SetEvictionTime(node.Name, evictAt)
+		if err != nil {
+			logging.Node(node.Name, node.Labels[c.PoolLabel]).WithField(logging.FieldAction, ActionAnnounce).
+				Errorf("failed to annotate pods with eviction time: %v", err)
+		}
+	}
+	c.announcedAt = announcedAt
+}
+
+// restoreAnnouncement return when the eviction of the node was announced according to the eviction time on its
+// pods, which is EvictionNotice after the announcement at the latest. Now when nothing was announced yet.
+func (c *Client) restoreAnnouncement(node corev1.Node, now time.Time) time.Time {
+	evictAt, err := c.Cluster.GetEvictionTime(node.Name)
+	if err != nil {
+		logging.Node(node.Name, node.Labels[c.PoolLabel]).WithField(logging.FieldAction, ActionAnnounce).
+			Warnf("failed to get announced eviction time: %v", err)
+		return now
+	}
+
+	if announcedAt := evictAt.Add(-c.EvictionNotice); !evictAt.IsZero() && announcedAt.Before(now) {
+		return announcedAt
+	}
+	return now
+}
+
+// noticeEnd return when the notice of a node announced at the time is over, capped at the latest time its
+// processing may start
+func (c *Client) noticeEnd(announcedAt time.Time, expiredAt time.Time) time.Time {
+	end := announcedAt.Add(c.EvictionNotice)
+	if latest := c.latestStart(expiredAt); latest.Before(end) {
+		return latest
+	}
+	return end
+}
+
+// latestStart return the last time processing of a node expiring at the time may start to be done before it
+// expires, or before the next peak hour when it expires during it
+func (c *Client) latestStart(expiredAt time.Time) time.Time {
+	latest := expiredAt
+	if !c.PeakHours.IsPeakHourNow() {
+		if startPeakHour := c.PeakHours.GetNearestStartPeakHour(); startPeakHour.Before(latest) {
+			latest = startPeakHour
+		}
+	}
+
+	return latest.Add(-c.processTimeout())
+}
+
+// holdForNotice record the node as held back until EvictionNotice has passed since its eviction was announced,
+// the main loop wakes up once the first held node is released. Nodes are not held past the latest time their
+// processing may start.
+func (c *Client) holdForNotice(node corev1.Node, logger *log.Entry, decision audit.Record) bool {
+	announcedAt, ok := c.announcedAt[node.Name]
+	if c.EvictionNotice <= 0 || !ok {
+		return false
+	}
+
+	until := c.noticeEnd(announcedAt, c.Cluster.GetNodeCreatedTime(node).Add(c.MaxLifetime))
+	if !peakhour.Now().Before(until) {
+		return false
+	}
+
+	logger.WithField(logging.FieldAction, ActionSkip).Infof("eviction announced at %s, holding node until %s",
+		announcedAt.Format(time.RFC3339), until.Format(time.RFC3339))
+	decision.Rule = audit.RuleEvictionNotice
+	decision.Action = ActionSkip
+	c.recordDecision(decision, nil)
+	if c.noticeHeldUntil.IsZero() || until.Before(c.noticeHeldUntil) {
+		c.noticeHeldUntil = until
+	}
+	return true
+}
+
+// announceAllEvictions is announceEvictions for every managed node
+func (c *Client) announceAllEvictions() {
+	if c.EvictionNotice <= 0 {
+		return
+	}
+
+	nodes, err := c.Cluster.GetPreemptibleNodes()
+	if err != nil {
+		log.WithField(logging.FieldAction, ActionAnnounce).Errorf("failed to get preemptible nodes: %v", err)
+		return
+	}
+
+	c.announceEvictions(nodes.Items)
+}
+
+// heldSleep shorten a sleep so the loop wakes up once the first node held for its eviction notice is released
+func (c *Client) heldSleep(d time.Duration) time.Duration {
+	if c.noticeHeldUntil.IsZero() {
+		return d
+	}
+
+	if held := c.noticeHeldUntil.Sub(peakhour.Now()); held < d {
+		return held
+	}
+	return d
+}
+
+// noticeSleep shorten a sleep until the next schedule so the loop wakes up EvictionNotice ahead of it,
+// in time to announce the evictions.
+func (c *Client) noticeSleep(d time.Duration) time.Duration {
+	if c.EvictionNotice <= 0 || d <= c.EvictionNotice {
+		return d
+	}
+
+	return d - c.EvictionNotice
+}
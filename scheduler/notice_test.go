@@ -0,0 +1,183 @@
+package scheduler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"preemptible-lifecycle-scheduler/provider"
+	"testing"
+	"time"
+)
+
+func TestClient_AnnounceEvictions(t *testing.T) {
+	day := func(hour int, minute int) time.Time {
+		return time.Date(1, 1, 2, hour, minute, 0, 0, time.Now().Location())
+	}
+	peakhour.Now = func() time.Time {
+		return day(7, 0)
+	}
+
+	newNode := func(name string, expiredAt time.Time, annotations map[string]string) corev1.Node {
+		return corev1.Node{ObjectMeta: v1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: v1.Time{Time: expiredAt.Add(-provider.DefaultMaxLifetime)},
+			Annotations:       annotations,
+		}}
+	}
+
+	ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	mockCluster := NewMockClusterClient()
+	mockCluster.EvictionTimes = make(map[string]time.Time)
+	client := NewClient(mockCluster, ph, 15)
+	client.EvictionNotice = 30 * time.Minute
+
+	client.announceEvictions([]corev1.Node{
+		newNode("expiring", day(8, 0), nil),
+		newNode("not expiring", day(18, 0), nil),
+		newNode("skipped", day(8, 0), map[string]string{cluster.AnnotationSkip: "2020-01-01T00:00:00Z"}),
+	})
+
+	expected := map[string]time.Time{
+		"expiring":     day(7, 30),
+		"not expiring": {},
+		"skipped":      {},
+	}
+	for node, evictAt := range expected {
+		if !mockCluster.EvictionTimes[node].Equal(evictAt) {
+			t.Errorf("expected %s evicted at %v, got %v", node, evictAt, mockCluster.EvictionTimes[node])
+		}
+	}
+}
+
+func TestClient_NoticeSleep(t *testing.T) {
+	tests := map[string]struct {
+		EvictionNotice time.Duration
+		Sleep          time.Duration
+		Expected       time.Duration
+	}{
+		"disabled": {
+			Sleep:    2 * time.Hour,
+			Expected: 2 * time.Hour,
+		},
+		"wake up ahead": {
+			EvictionNotice: 30 * time.Minute,
+			Sleep:          2 * time.Hour,
+			Expected:       90 * time.Minute,
+		},
+		"within notice": {
+			EvictionNotice: 30 * time.Minute,
+			Sleep:          20 * time.Minute,
+			Expected:       20 * time.Minute,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := &Client{EvictionNotice: tc.EvictionNotice}
+			result := client.noticeSleep(tc.Sleep)
+			if result != tc.Expected {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
+
+func TestClient_HoldForNotice(t *testing.T) {
+	now := time.Date(1, 1, 2, 7, 0, 0, 0, time.UTC)
+	ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	tests := map[string]struct {
+		Now       time.Time
+		ExpiredAt time.Time
+		// AnnouncedAt is kept in memory, Annotated is the eviction time left on pods before a restart
+		AnnouncedAt     time.Time
+		Annotated       time.Time
+		Expected        []string
+		ExpectedEvictAt time.Time
+	}{
+		"announced now": {
+			ExpiredAt:       now.Add(25 * time.Minute),
+			ExpectedEvictAt: now.Add(20 * time.Minute),
+		},
+		"notice passed": {
+			ExpiredAt:       now.Add(25 * time.Minute),
+			AnnouncedAt:     now.Add(-30 * time.Minute),
+			Expected:        []string{"expiring"},
+			ExpectedEvictAt: now,
+		},
+		"announced late": {
+			ExpiredAt:       now.Add(10 * time.Minute),
+			ExpectedEvictAt: now.Add(5 * time.Minute),
+		},
+		"announced too late to hold": {
+			ExpiredAt:       now.Add(4 * time.Minute),
+			Expected:        []string{"expiring"},
+			ExpectedEvictAt: now.Add(-1 * time.Minute),
+		},
+		"held across a restart": {
+			ExpiredAt:       now.Add(25 * time.Minute),
+			Annotated:       now.Add(5 * time.Minute),
+			ExpectedEvictAt: now.Add(5 * time.Minute),
+		},
+		"notice passed before a restart": {
+			ExpiredAt:       now.Add(25 * time.Minute),
+			Annotated:       now.Add(-1 * time.Minute),
+			Expected:        []string{"expiring"},
+			ExpectedEvictAt: now.Add(-1 * time.Minute),
+		},
+		"peak hour starting": {
+			Now:             now.Add(2*time.Hour + 40*time.Minute),
+			ExpiredAt:       now.Add(5 * time.Hour),
+			ExpectedEvictAt: now.Add(2*time.Hour + 55*time.Minute),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			current := now
+			if !tc.Now.IsZero() {
+				current = tc.Now
+			}
+			peakhour.Now = func() time.Time {
+				return current
+			}
+
+			node := corev1.Node{ObjectMeta: v1.ObjectMeta{
+				Name:              "expiring",
+				CreationTimestamp: v1.Time{Time: tc.ExpiredAt.Add(-provider.DefaultMaxLifetime)},
+			}}
+
+			mockCluster := NewMockClusterClient()
+			mockCluster.EvictionTimes = map[string]time.Time{node.Name: tc.Annotated}
+			client := NewClient(mockCluster, ph, 15)
+			client.EvictionNotice = 30 * time.Minute
+			client.ProcessTimeout = 5 * time.Minute
+			if !tc.AnnouncedAt.IsZero() {
+				client.announcedAt = map[string]time.Time{node.Name: tc.AnnouncedAt}
+			}
+
+			client.announceEvictions([]corev1.Node{node})
+			if client.GetPeakHourState() == StartPeakHour {
+				client.ProcessNodesStartPeakHour([]corev1.Node{node})
+			} else {
+				client.ProcessNodesOutsidePeakHour([]corev1.Node{node})
+			}
+
+			if len(mockCluster.ProcessedNodes) != len(tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, mockCluster.ProcessedNodes)
+			}
+
+			if !mockCluster.EvictionTimes[node.Name].Equal(tc.ExpectedEvictAt) {
+				t.Errorf("expected %v, got %v", tc.ExpectedEvictAt, mockCluster.EvictionTimes[node.Name])
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package scheduler
+
+import (
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/logging"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"sort"
+	"time"
+)
+
+// Plan is what the scheduler is going to do next, as served by the status api
+type Plan struct {
+	PeakState     string             `json:"peakState"`
+	NextPeakStart time.Time          `json:"nextPeakStart"`
+	NextPeakEnd   time.Time          `json:"nextPeakEnd"`
+	NextWakeup    time.Time          `json:"nextWakeup"`
+	Pause         cluster.PauseState `json:"pause"`
+	Nodes         []PlannedNode      `json:"nodes"`
+	InFlight      []InFlightNode     `json:"inFlight"`
+}
+
+type PlannedNode struct {
+	Node      string    `json:"node"`
+	Pool      string    `json:"pool,omitempty"`
+	Zone      string    `json:"zone,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Age       string    `json:"age"`
+	ExpiredAt time.Time `json:"expiredAt"`
+	Action    string    `json:"action"`
+	PlannedAt time.Time `json:"plannedAt,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	// Order is the rank of the node when nodes are due at the same time, starting at 1
+	Order int `json:"order"`
+	// Score is the score of the node in the last scan, 0 for nodes not scanned yet
+	Score float64 `json:"score"`
+}
+
+// GetPlan list managed nodes with the time each of them is going to be recycled
+func (c *Client) GetPlan() (*Plan, error) {
+	nodes, err := c.Cluster.GetPreemptibleNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	nextWakeup := c.nextWakeup
+	c.mu.Unlock()
+
+	now := peakhour.Now()
+	plan := &Plan{
+		PeakState:     c.GetPeakHourState(),
+		NextPeakStart: c.PeakHours.GetNearestStartPeakHour(),
+		NextPeakEnd:   c.PeakHours.GetNearestEndPeakHour(),
+		NextWakeup:    nextWakeup,
+		Pause:         c.GetPauseState(),
+		Nodes:         make([]PlannedNode, 0),
+		InFlight:      c.GetInFlightNodes(),
+	}
+
+	for i, scored := range c.plannedOrder(nodes.Items) {
+		node := scored.Node
+		createdAt := c.Cluster.GetNodeCreatedTime(node)
+		planned := PlannedNode{
+			Node:      node.Name,
+			Pool:      node.Labels[c.PoolLabel],
+			Zone:      c.zoneOf(node),
+			CreatedAt: createdAt,
+			Age:       now.Sub(createdAt).Round(time.Minute).String(),
+			ExpiredAt: createdAt.Add(c.MaxLifetime),
+			Action:    ActionSkip,
+			Order:     i + 1,
+			Score:     scored.Score,
+		}
+
+		planned.Reason = PlanReasonSkipped
+		if !cluster.IsNodeSkipped(&node) {
+			planned.Action = ActionRecycle
+			planned.PlannedAt, planned.Reason = c.plan(planned.ExpiredAt)
+		}
+
+		plan.Nodes = append(plan.Nodes, planned)
+	}
+
+	sort.Slice(plan.InFlight, func(i, j int) bool {
+		return plan.InFlight[i].StartedAt.Before(plan.InFlight[j].StartedAt)
+	})
+
+	return plan, nil
+}
+
+// plannedOrder order nodes like orderNodes with the scores of the last scan, scorers may list the pods of every
+// node and must not run on each status request. Nodes not scanned yet come last, by expiry.
+func (c *Client) plannedOrder(nodes []corev1.Node) []ScoredNode {
+	c.mu.Lock()
+	scores := c.scores
+	c.mu.Unlock()
+
+	ordered := make([]ScoredNode, len(nodes))
+	for i, node := range nodes {
+		ordered[i] = ScoredNode{Node: node, Score: scores[node.Name]}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		_, scannedI := scores[ordered[i].Node.Name]
+		_, scannedJ := scores[ordered[j].Node.Name]
+		if scannedI != scannedJ {
+			return scannedI
+		}
+		if ordered[i].Score != ordered[j].Score {
+			return ordered[i].Score < ordered[j].Score
+		}
+		return c.Cluster.GetNodeCreatedTime(ordered[i].Node).Before(c.Cluster.GetNodeCreatedTime(ordered[j].Node))
+	})
+
+	return c.interleaveZones(ordered)
+}
+
+const (
+	// reasons a node is recycled at its planned time, published on the node condition
+	PlanReasonExpiring       = "Expiring"
+	PlanReasonBeforePeakHour = "BeforePeakHour"
+	PlanReasonAfterPeakHour  = "AfterPeakHour"
+	PlanReasonOverdue        = "Overdue"
+	PlanReasonSkipped        = "SkippedByOperator"
+)
+
+// PlannedAt return when a node expiring at the time is going to be recycled according to peak hour rules:
+// nodes are recycled within graceful period before they expire, at the latest right before a peak hour they
+// would not survive, and never during a peak hour. Overdue nodes keep the time they were due at, so the plan
+// published on them does not change with every scan.
+func (c *Client) PlannedAt(expiredAt time.Time) time.Time {
+	plannedAt, _ := c.plan(expiredAt)
+	return plannedAt
+}
+
+// plan is PlannedAt along with the rule that decided the time
+func (c *Client) plan(expiredAt time.Time) (time.Time, string) {
+	now := peakhour.Now()
+	plannedAt := expiredAt.Add(-c.GracefulPeriod)
+
+	if c.PeakHours.IsPeakHourNow() {
+		endPeakHour := c.PeakHours.GetNearestEndPeakHour()
+		if plannedAt.Before(endPeakHour) {
+			return endPeakHour, PlanReasonAfterPeakHour
+		}
+		return plannedAt, PlanReasonExpiring
+	}
+
+	reason := PlanReasonExpiring
+	startPeakHour := c.PeakHours.GetNearestStartPeakHour().Add(-c.GracefulPeriod)
+	endPeakHour := c.PeakHours.GetNearestEndPeakHour()
+	if plannedAt.After(startPeakHour) && !expiredAt.After(endPeakHour) {
+		plannedAt = startPeakHour
+		reason = PlanReasonBeforePeakHour
+	}
+
+	if plannedAt.Before(now) {
+		return plannedAt, PlanReasonOverdue
+	}
+	return plannedAt, reason
+}
+
+// publishPlan write the planned recycle time of every node on the node itself, so other tooling can tell
+// when nodes are going away. It runs after every scan since the plan changes with time and peak hours.
+func (c *Client) publishPlan(nodes []corev1.Node) {
+	for _, node := range nodes {
+		expiredAt := c.Cluster.GetNodeCreatedTime(node).Add(c.MaxLifetime)
+		plannedAt, reason := time.Time{}, PlanReasonSkipped
+		message := "node was skipped by an operator"
+		if !cluster.IsNodeSkipped(&node) {
+			plannedAt, reason = c.plan(expiredAt)
+			message = fmt.Sprintf("recycle planned at %s, node expires at %s",
+				plannedAt.UTC().Format(time.RFC3339), expiredAt.UTC().Format(time.RFC3339))
+		}
+
+		err := c.Cluster.SetRecyclePlan(&node, plannedAt, reason, message)
+		if err != nil {
+			logging.Node(node.Name, node.Labels[c.PoolLabel]).WithField(logging.FieldAction, ActionScan).
+				Errorf("failed to publish recycle plan: %v", err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package scheduler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"preemptible-lifecycle-scheduler/provider"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_PlannedAt(t *testing.T) {
+	day := func(hour int, minute int) time.Time {
+		return time.Date(1, 1, 2, hour, minute, 0, 0, time.Now().Location())
+	}
+
+	tests := map[string]struct {
+		CurrentTime time.Time
+		ExpiredAt   time.Time
+		Expected    time.Time
+	}{
+		"outside peak hour, expiring after next peak hour": {
+			CurrentTime: day(7, 0),
+			ExpiredAt:   day(18, 0),
+			Expected:    day(17, 30),
+		},
+		"outside peak hour, expiring during next peak hour": {
+			CurrentTime: day(7, 0),
+			ExpiredAt:   day(12, 0),
+			Expected:    day(9, 30),
+		},
+		"outside peak hour, expiring before next peak hour": {
+			CurrentTime: day(7, 0),
+			ExpiredAt:   day(9, 0),
+			Expected:    day(8, 30),
+		},
+		"outside peak hour, overdue": {
+			CurrentTime: day(7, 0),
+			ExpiredAt:   day(7, 10),
+			Expected:    day(6, 40),
+		},
+		"outside peak hour, still overdue on next scan": {
+			CurrentTime: day(7, 5),
+			ExpiredAt:   day(7, 10),
+			Expected:    day(6, 40),
+		},
+		"in peak hour, expiring during peak hour": {
+			CurrentTime: day(11, 0),
+			ExpiredAt:   day(12, 0),
+			Expected:    day(15, 0),
+		},
+		"in peak hour, expiring after peak hour": {
+			CurrentTime: day(11, 0),
+			ExpiredAt:   day(20, 0),
+			Expected:    day(19, 30),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			peakhour.Now = func() time.Time {
+				return tc.CurrentTime
+			}
+
+			ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+			if err != nil {
+				t.Fatalf("failed to create peak hour client %v", err)
+			}
+
+			client := NewClient(NewMockClusterClient(), ph, 15)
+			result := client.PlannedAt(tc.ExpiredAt)
+			if !result.Equal(tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, result)
+			}
+		})
+	}
+}
+
+func TestClient_PublishPlan(t *testing.T) {
+	day := func(hour int, minute int) time.Time {
+		return time.Date(1, 1, 2, hour, minute, 0, 0, time.Now().Location())
+	}
+	peakhour.Now = func() time.Time {
+		return day(7, 0)
+	}
+
+	newNode := func(name string, expiredAt time.Time, annotations map[string]string) corev1.Node {
+		return corev1.Node{ObjectMeta: v1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: v1.Time{Time: expiredAt.Add(-provider.DefaultMaxLifetime)},
+			Annotations:       annotations,
+		}}
+	}
+
+	ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	mockCluster := NewMockClusterClient()
+	mockCluster.RecyclePlans = make(map[string]string)
+	client := NewClient(mockCluster, ph, 15)
+	client.publishPlan([]corev1.Node{
+		newNode("expiring", day(18, 0), nil),
+		newNode("expiring in peak hour", day(12, 0), nil),
+		newNode("overdue", day(7, 10), nil),
+		newNode("skipped", day(8, 0), map[string]string{cluster.AnnotationSkip: "2020-01-01T00:00:00Z"}),
+	})
+
+	expected := map[string]string{
+		"expiring":              PlanReasonExpiring,
+		"expiring in peak hour": PlanReasonBeforePeakHour,
+		"overdue":               PlanReasonOverdue,
+		"skipped":               PlanReasonSkipped,
+	}
+	for node, reason := range expected {
+		if mockCluster.RecyclePlans[node] != reason {
+			t.Errorf("expected %s planned for %v, got %v", node, reason, mockCluster.RecyclePlans[node])
+		}
+	}
+}
+
+func TestClient_GetPlan_ScoresOfLastScan(t *testing.T) {
+	peakhour.Now = func() time.Time {
+		return time.Date(1, 1, 2, 7, 0, 0, 0, time.Now().Location())
+	}
+
+	ph, err := peakhour.NewClient([]string{"10:00-15:00"})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	createdAt := time.Date(1, 1, 1, 10, 0, 0, 0, time.Now().Location())
+	newNode := func(name string, createdAt time.Time) corev1.Node {
+		return corev1.Node{ObjectMeta: v1.ObjectMeta{Name: name, CreationTimestamp: v1.Time{Time: createdAt}}}
+	}
+
+	cc := NewMockClusterClient()
+	cc.Nodes = []corev1.Node{newNode("busy", createdAt), newNode("empty", createdAt.Add(time.Minute))}
+	cc.Pods = map[string][]corev1.Pod{"busy": {newScoredPod(0, 10), newScoredPod(0, 10)}}
+	client := NewClient(cc, ph, 15)
+	podCount, err := NewWeightedScorer(ScorerPodCount, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.Scorers = []WeightedScorer{podCount}
+
+	client.orderNodes(cc.Nodes)
+	listings := cc.PodListings
+
+	// a node created after the scan has no score yet
+	cc.Nodes = append(cc.Nodes, newNode("new", createdAt.Add(-time.Minute)))
+	plan, err := client.GetPlan()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cc.PodListings != listings {
+		t.Errorf("expected %v pod listings, got %v", listings, cc.PodListings)
+	}
+
+	names := make([]string, 0)
+	for _, node := range plan.Nodes {
+		names = append(names, node.Node)
+	}
+	expected := []string{"empty", "busy", "new"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
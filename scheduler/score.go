@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"math"
+	"preemptible-lifecycle-scheduler/logging"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ScorerExpiry      = "expiry"
+	ScorerPodCount    = "pod-count"
+	ScorerPodPriority = "pod-priority"
+	ScorerDrainCost   = "drain-cost"
+
+	// defaultTerminationGracePeriod is what kubernetes gives pods that don't set one, in seconds
+	defaultTerminationGracePeriod = 30
+)
+
+// NodeScorer rate a node, nodes with a lower score are recycled first. Scores of a scorer are only compared
+// with each other, so any scale will do.
+type NodeScorer interface {
+	Score(candidate *Candidate) float64
+}
+
+// ScorerFunc turn a function into a NodeScorer
+type ScorerFunc func(candidate *Candidate) float64
+
+func (f ScorerFunc) Score(candidate *Candidate) float64 {
+	return f(candidate)
+}
+
+// WeightedScorer is a scorer along with how much it counts in the final score of a node
+type WeightedScorer struct {
+	Name   string
+	Scorer NodeScorer
+	Weight float64
+}
+
+// NewWeightedScorer return the built-in scorer with the name, a weight of 0 counts as 1
+func NewWeightedScorer(name string, weight float64) (WeightedScorer, error) {
+	if weight == 0 {
+		weight = 1
+	}
+
+	scorers := map[string]ScorerFunc{
+		ScorerExpiry:      expiryScore,
+		ScorerPodCount:    podCountScore,
+		ScorerPodPriority: podPriorityScore,
+		ScorerDrainCost:   drainCostScore,
+	}
+	scorer, ok := scorers[name]
+	if !ok {
+		return WeightedScorer{}, fmt.Errorf("unknown node scorer: %s", name)
+	}
+
+	return WeightedScorer{Name: name, Scorer: scorer, Weight: weight}, nil
+}
+
+// Candidate is a node being scored, its pods are listed on first use since most scorers don't need them
+type Candidate struct {
+	Node      corev1.Node
+	ExpiredAt time.Time
+
+	cluster ClusterClient
+	pods    []corev1.Pod
+	listed  bool
+}
+
+// Pods return the pods evicted when the node is recycled, none when they can't be listed
+func (c *Candidate) Pods() []corev1.Pod {
+	if c.listed {
+		return c.pods
+	}
+
+	c.listed = true
+	pods, err := c.cluster.GetPods(c.Node.Name)
+	if err != nil {
+		logging.Node(c.Node.Name, "").WithField(logging.FieldAction, ActionScan).Warnf("failed to list pods for scoring: %v", err)
+		return nil
+	}
+
+	c.pods = pods
+	return c.pods
+}
+
+// expiryScore put nodes expiring soonest first
+func expiryScore(candidate *Candidate) float64 {
+	return float64(candidate.ExpiredAt.Unix())
+}
+
+// podCountScore put nodes hosting the fewest pods first
+func podCountScore(candidate *Candidate) float64 {
+	return float64(len(candidate.Pods()))
+}
+
+// podPriorityScore put nodes whose most important pod has the lowest priority first, empty nodes before all
+func podPriorityScore(candidate *Candidate) float64 {
+	highest := float64(math.MinInt32)
+	for _, pod := range candidate.Pods() {
+		priority := float64(0)
+		if pod.Spec.Priority != nil {
+			priority = float64(*pod.Spec.Priority)
+		}
+		highest = math.Max(highest, priority)
+	}
+
+	return highest
+}
+
+// drainCostScore put nodes whose pods take the least time to terminate first
+func drainCostScore(candidate *Candidate) float64 {
+	var cost int64
+	for _, pod := range candidate.Pods() {
+		gracePeriod := int64(defaultTerminationGracePeriod)
+		if pod.Spec.TerminationGracePeriodSeconds != nil {
+			gracePeriod = *pod.Spec.TerminationGracePeriodSeconds
+		}
+		cost += gracePeriod
+	}
+
+	return float64(cost)
+}
+
+// ScoredNode is a node along with its final score, lower is recycled first
+type ScoredNode struct {
+	Node  corev1.Node
+	Score float64
+}
+
+// scoreNodes rate nodes with every scorer, sorted by score. Scores of each scorer are scaled between 0 and 1
+// before they are weighted and summed, so no scorer outweighs another because of its unit. Nodes are rated by
+// expiry alone when no scorer is set.
+func (c *Client) scoreNodes(nodes []corev1.Node) []ScoredNode {
+	scorers := c.Scorers
+	if len(scorers) == 0 {
+		scorers = []WeightedScorer{{Name: ScorerExpiry, Scorer: ScorerFunc(expiryScore), Weight: 1}}
+	}
+
+	candidates := make([]*Candidate, len(nodes))
+	for i, node := range nodes {
+		candidates[i] = &Candidate{
+			Node:      node,
+			ExpiredAt: c.Cluster.GetNodeCreatedTime(node).Add(c.MaxLifetime),
+			cluster:   c.Cluster,
+		}
+	}
+
+	scored := make([]ScoredNode, len(nodes))
+	for i, node := range nodes {
+		scored[i].Node = node
+	}
+
+	for _, scorer := range scorers {
+		scores := make([]float64, len(candidates))
+		lowest, highest := math.Inf(1), math.Inf(-1)
+		for i, candidate := range candidates {
+			scores[i] = scorer.Scorer.Score(candidate)
+			lowest = math.Min(lowest, scores[i])
+			highest = math.Max(highest, scores[i])
+		}
+
+		if highest == lowest {
+			continue
+		}
+		for i := range scored {
+			scored[i].Score += scorer.Weight * (scores[i] - lowest) / (highest - lowest)
+		}
+	}
+
+	// ties go to the node expiring first
+	order := make([]int, len(scored))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if scored[a].Score != scored[b].Score {
+			return scored[a].Score < scored[b].Score
+		}
+		return candidates[a].ExpiredAt.Before(candidates[b].ExpiredAt)
+	})
+
+	sorted := make([]ScoredNode, len(scored))
+	for i, index := range order {
+		sorted[i] = scored[index]
+	}
+	return sorted
+}
+
+// logOrder log the order nodes are going to be considered in, along with their score
+func (c *Client) logOrder(nodes []ScoredNode, state string) {
+	if len(nodes) < 2 {
+		return
+	}
+
+	order := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		order = append(order, fmt.Sprintf("%s (%.3f)", node.Node.Name, node.Score))
+	}
+
+	log.WithFields(log.Fields{
+		logging.FieldState:  state,
+		logging.FieldAction: ActionScan,
+	}).Infof("recycle order: %s", strings.Join(order, ", "))
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newScoredPod(priority int32, gracePeriod int64) corev1.Pod {
+	return corev1.Pod{Spec: corev1.PodSpec{Priority: &priority, TerminationGracePeriodSeconds: &gracePeriod}}
+}
+
+func TestClient_ScoreNodes(t *testing.T) {
+	createdAt := time.Date(1, 1, 1, 10, 0, 0, 0, time.UTC)
+	nodes := []corev1.Node{
+		{ObjectMeta: v1.ObjectMeta{Name: "busy", CreationTimestamp: v1.Time{Time: createdAt}}},
+		{ObjectMeta: v1.ObjectMeta{Name: "critical", CreationTimestamp: v1.Time{Time: createdAt.Add(1 * time.Minute)}}},
+		{ObjectMeta: v1.ObjectMeta{Name: "slow", CreationTimestamp: v1.Time{Time: createdAt.Add(2 * time.Minute)}}},
+		{ObjectMeta: v1.ObjectMeta{Name: "empty", CreationTimestamp: v1.Time{Time: createdAt.Add(3 * time.Minute)}}},
+	}
+	pods := map[string][]corev1.Pod{
+		"busy":     {newScoredPod(0, 10), newScoredPod(0, 10), newScoredPod(0, 10)},
+		"critical": {newScoredPod(1000000, 10)},
+		"slow":     {newScoredPod(100, 600)},
+	}
+
+	tests := map[string]struct {
+		Scorers  map[string]float64
+		Expected []string
+	}{
+		"expiry by default": {
+			Expected: []string{"busy", "critical", "slow", "empty"},
+		},
+		"pod count": {
+			Scorers:  map[string]float64{ScorerPodCount: 1},
+			Expected: []string{"empty", "critical", "slow", "busy"},
+		},
+		"pod priority": {
+			Scorers:  map[string]float64{ScorerPodPriority: 1},
+			Expected: []string{"empty", "busy", "slow", "critical"},
+		},
+		"drain cost": {
+			Scorers:  map[string]float64{ScorerDrainCost: 1},
+			Expected: []string{"empty", "critical", "busy", "slow"},
+		},
+		"weighted": {
+			Scorers:  map[string]float64{ScorerExpiry: 2, ScorerDrainCost: 1},
+			Expected: []string{"busy", "critical", "empty", "slow"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cc := NewMockClusterClient()
+			cc.Pods = pods
+			client := NewClient(cc, nil, 15)
+			for scorer, weight := range tc.Scorers {
+				weighted, err := NewWeightedScorer(scorer, weight)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				client.Scorers = append(client.Scorers, weighted)
+			}
+
+			names := make([]string, 0)
+			for _, node := range client.scoreNodes(nodes) {
+				names = append(names, node.Node.Name)
+			}
+
+			if !reflect.DeepEqual(names, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, names)
+			}
+		})
+	}
+}
+
+func TestNewWeightedScorer(t *testing.T) {
+	scorer, err := NewWeightedScorer(ScorerDrainCost, 0)
+	if err != nil || scorer.Weight != 1 {
+		t.Errorf("expected weight 1, got %+v, %v", scorer, err)
+	}
+
+	_, err = NewWeightedScorer("unknown", 1)
+	if err == nil {
+		t.Errorf("expected error for unknown scorer")
+	}
+}
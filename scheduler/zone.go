@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"preemptible-lifecycle-scheduler/audit"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/logging"
+)
+
+const DefaultZoneLabel = "topology.kubernetes.io/zone"
+
+// zoneOf return the zone of the node from ZoneLabel, falling back to the legacy zone label of older clusters
+func (c *Client) zoneOf(node corev1.Node) string {
+	if zone, ok := node.Labels[c.ZoneLabel]; ok && c.ZoneLabel != "" {
+		return zone
+	}
+
+	return node.Labels[corev1.LabelZoneFailureDomain]
+}
+
+// orderNodes sort nodes by score then interleave their zones, so nodes recycled one after another are in
+// different zones whenever possible. Zones take turns in the order of their best scored node.
+func (c *Client) orderNodes(nodes []corev1.Node) []ScoredNode {
+	scored := c.scoreNodes(nodes)
+
+	scores := make(map[string]float64, len(scored))
+	for _, node := range scored {
+		scores[node.Node.Name] = node.Score
+	}
+	c.mu.Lock()
+	c.scores = scores
+	c.mu.Unlock()
+
+	return c.interleaveZones(scored)
+}
+
+// interleaveZones pick one node of every zone in turn, keeping the order of nodes within a zone
+func (c *Client) interleaveZones(nodes []ScoredNode) []ScoredNode {
+	zones := make([]string, 0)
+	byZone := make(map[string][]ScoredNode)
+	for _, node := range nodes {
+		zone := c.zoneOf(node.Node)
+		if _, ok := byZone[zone]; !ok {
+			zones = append(zones, zone)
+		}
+		byZone[zone] = append(byZone[zone], node)
+	}
+
+	ordered := make([]ScoredNode, 0, len(nodes))
+	for len(ordered) < len(nodes) {
+		for _, zone := range zones {
+			if len(byZone[zone]) == 0 {
+				continue
+			}
+
+			ordered = append(ordered, byZone[zone][0])
+			byZone[zone] = byZone[zone][1:]
+		}
+	}
+
+	return ordered
+}
+
+// isZoneFull tell whether MaxDisruptedPerZone nodes of the zone of the node are disrupted already, either
+// being processed, cordoned or tainted for recycling, and return the zone along with how many there are
+func (c *Client) isZoneFull(node corev1.Node, nodes []corev1.Node) (bool, string, int) {
+	zone := c.zoneOf(node)
+	if c.MaxDisruptedPerZone <= 0 {
+		return false, zone, 0
+	}
+
+	disrupted := make(map[string]struct{})
+	for _, inFlight := range c.GetInFlightNodes() {
+		if inFlight.Zone == zone {
+			disrupted[inFlight.Node] = struct{}{}
+		}
+	}
+
+	for _, n := range nodes {
+		// nodes tainted for recycling are disrupted even when they are not cordoned
+		if (n.Spec.Unschedulable || cluster.HasRecyclingTaint(&n)) && c.zoneOf(n) == zone {
+			disrupted[n.Name] = struct{}{}
+		}
+	}
+
+	// the node itself does not count, processing it does not disrupt its zone any further
+	delete(disrupted, node.Name)
+	return len(disrupted) >= c.MaxDisruptedPerZone, zone, len(disrupted)
+}
+
+// holdForZone record the node as held back when its zone is full, it is retried soon like a failed node
+func (c *Client) holdForZone(node corev1.Node, nodes []corev1.Node, logger *log.Entry, decision audit.Record) bool {
+	full, zone, disrupted := c.isZoneFull(node, nodes)
+	if !full {
+		return false
+	}
+
+	logger.WithField(logging.FieldAction, ActionSkip).Warnf("zone %q already has %d disrupted nodes, holding node back", zone, disrupted)
+	decision.Rule = audit.RuleZoneLimit
+	decision.Action = ActionSkip
+	c.recordDecision(decision, nil)
+	c.retryNodes++
+	return true
+}
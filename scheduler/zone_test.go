@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"preemptible-lifecycle-scheduler/cluster"
+	"preemptible-lifecycle-scheduler/peakhour"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newZoneNode(name string, zone string, createdAt time.Time) corev1.Node {
+	return corev1.Node{ObjectMeta: v1.ObjectMeta{
+		Name:              name,
+		Labels:            map[string]string{DefaultZoneLabel: zone},
+		CreationTimestamp: v1.Time{Time: createdAt},
+	}}
+}
+
+func TestClient_OrderNodes(t *testing.T) {
+	createdAt := time.Date(1, 1, 1, 10, 0, 0, 0, time.UTC)
+	legacy := corev1.Node{ObjectMeta: v1.ObjectMeta{
+		Name:              "c-1",
+		Labels:            map[string]string{corev1.LabelZoneFailureDomain: "zone-c"},
+		CreationTimestamp: v1.Time{Time: createdAt.Add(5 * time.Minute)},
+	}}
+
+	tests := map[string]struct {
+		Nodes    []corev1.Node
+		Expected []string
+	}{
+		"by expiry in a single zone": {
+			Nodes: []corev1.Node{
+				newZoneNode("a-2", "zone-a", createdAt.Add(2*time.Minute)),
+				newZoneNode("a-1", "zone-a", createdAt.Add(1*time.Minute)),
+			},
+			Expected: []string{"a-1", "a-2"},
+		},
+		"zones interleaved": {
+			Nodes: []corev1.Node{
+				newZoneNode("a-1", "zone-a", createdAt),
+				newZoneNode("a-2", "zone-a", createdAt.Add(1*time.Minute)),
+				newZoneNode("a-3", "zone-a", createdAt.Add(2*time.Minute)),
+				newZoneNode("b-1", "zone-b", createdAt.Add(3*time.Minute)),
+				newZoneNode("b-2", "zone-b", createdAt.Add(4*time.Minute)),
+				legacy,
+			},
+			Expected: []string{"a-1", "b-1", "c-1", "a-2", "b-2", "a-3"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := NewClient(NewMockClusterClient(), nil, 15)
+
+			names := make([]string, 0)
+			for _, node := range client.orderNodes(tc.Nodes) {
+				names = append(names, node.Node.Name)
+			}
+
+			if !reflect.DeepEqual(names, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, names)
+			}
+		})
+	}
+}
+
+func TestClient_ProcessNodesOutsidePeakHour_ZoneLimit(t *testing.T) {
+	peakhour.Now = func() time.Time {
+		return time.Date(1, 1, 2, 10, 15, 0, 0, time.UTC)
+	}
+
+	ph, err := peakhour.NewClient([]string{})
+	if err != nil {
+		t.Fatalf("failed to create peak hour client %v", err)
+	}
+
+	createdAt := time.Date(1, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		MaxDisrupted  int
+		Tainted       bool
+		InFlight      string
+		Expected      []string
+		ExpectedRetry int
+	}{
+		"no limit": {
+			Expected: []string{"a-2", "b-1", "a-3"},
+		},
+		"zone with a cordoned node": {
+			MaxDisrupted:  1,
+			Expected:      []string{"b-1"},
+			ExpectedRetry: 2,
+		},
+		"zone with a tainted node": {
+			MaxDisrupted:  1,
+			Tainted:       true,
+			Expected:      []string{"b-1"},
+			ExpectedRetry: 2,
+		},
+		"zone with a node in flight": {
+			MaxDisrupted:  1,
+			InFlight:      "b-2",
+			ExpectedRetry: 3,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cc := NewMockClusterClient()
+			client := NewClient(cc, ph, 15)
+			client.MaxDisruptedPerZone = tc.MaxDisrupted
+			if tc.InFlight != "" {
+				client.startProcessing(tc.InFlight, "zone-b", "test")
+			}
+
+			// a-1 is being recycled, either cordoned or only tainted
+			disrupted := newZoneNode("a-1", "zone-a", createdAt.Add(24*time.Hour))
+			if tc.Tainted {
+				disrupted.Spec.Taints = []corev1.Taint{{Key: cluster.TaintKeyRecycling, Effect: corev1.TaintEffectNoSchedule}}
+			} else {
+				disrupted.Spec.Unschedulable = true
+			}
+
+			client.ProcessNodesOutsidePeakHour([]corev1.Node{
+				disrupted,
+				newZoneNode("a-2", "zone-a", createdAt),
+				newZoneNode("a-3", "zone-a", createdAt.Add(2*time.Minute)),
+				newZoneNode("b-1", "zone-b", createdAt.Add(1*time.Minute)),
+			})
+
+			if !reflect.DeepEqual(cc.ProcessedNodes, tc.Expected) {
+				t.Errorf("expected %v, got %v", tc.Expected, cc.ProcessedNodes)
+			}
+
+			if client.retryNodes != tc.ExpectedRetry {
+				t.Errorf("expected %v, got %v", tc.ExpectedRetry, client.retryNodes)
+			}
+		})
+	}
+}